@@ -0,0 +1,37 @@
+package tradfri
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetBlindPosition moves a blind to position, from 0 (open) to 100 (closed).
+func (c *Client) SetBlindPosition(deviceId int, position int) error {
+	return c.SetBlindPositionContext(context.Background(), deviceId, position)
+}
+
+func (c *Client) SetBlindPositionContext(ctx context.Context, deviceId int, position int) error {
+	if position < 0 || position > 100 {
+		return fmt.Errorf("Blind position must be 0-100, got %d", position)
+	}
+	p := float64(position)
+	return c.setBlind(ctx, deviceId, BlindControl{Position: &p})
+}
+
+// StopBlind stops a blind that is moving.
+func (c *Client) StopBlind(deviceId int) error {
+	return c.StopBlindContext(context.Background(), deviceId)
+}
+
+func (c *Client) StopBlindContext(ctx context.Context, deviceId int) error {
+	trigger := 0
+	return c.setBlind(ctx, deviceId, BlindControl{Trigger: &trigger})
+}
+
+func (c *Client) setBlind(ctx context.Context, deviceId int, change BlindControl) error {
+	payload := BlindSet{
+		[]BlindControl{change},
+	}
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.putRequest(ctx, uri, payload)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/urfave/cli"
+)
+
+func firmwareCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	report, err := client.GetFirmwareReport()
+	if report == nil {
+		checkErr(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tMODEL\tFIRMWARE\tSTATE")
+	for _, s := range append([]tradfri.FirmwareStatus{report.Gateway}, report.Devices...) {
+		id := "-"
+		if s.DeviceID != 0 {
+			id = fmt.Sprint(s.DeviceID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, s.Name, s.Type, s.Model, s.FirmwareVersion, s.State)
+	}
+	w.Flush()
+
+	if updating := report.Updating(); len(updating) > 0 {
+		fmt.Printf("\n%d updates pending or in progress\n", len(updating))
+	}
+	// report devices that could not be read after the table
+	checkErr(err)
+	return nil
+}
+
+func firmwareCheckCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.CheckForUpdates()
+	checkErr(err)
+	return nil
+}
+
+func firmwareInstallCommand(c *cli.Context) error {
+	if !c.Bool("confirm") {
+		return errors.New("the gateway reboots to install: pass --confirm to install")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.InstallGatewayUpdate()
+	checkErr(err)
+	return nil
+}
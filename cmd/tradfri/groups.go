@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+func groupCreateCommand(c *cli.Context) error {
+	if c.String("name") == "" {
+		return errors.New("required arguments: --name")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	id, err := client.CreateGroup(c.String("name"), c.IntSlice("device"))
+	checkErr(err)
+	fmt.Printf("Created group %d\n", id)
+	return nil
+}
+
+func groupRenameCommand(c *cli.Context) error {
+	if !c.IsSet("id") || c.String("name") == "" {
+		return errors.New("required arguments: --id --name")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.RenameGroup(c.Int("id"), c.String("name"))
+	checkErr(err)
+	return nil
+}
+
+func groupDeleteCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.DeleteGroup(c.Int("id"))
+	checkErr(err)
+	return nil
+}
+
+func groupAddCommand(c *cli.Context) error {
+	if !c.IsSet("id") || len(c.IntSlice("device")) == 0 {
+		return errors.New("required arguments: --id --device")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.AddToGroup(c.Int("id"), c.IntSlice("device")...)
+	checkErr(err)
+	return nil
+}
+
+func groupRemoveCommand(c *cli.Context) error {
+	if !c.IsSet("id") || len(c.IntSlice("device")) == 0 {
+		return errors.New("required arguments: --id --device")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.RemoveFromGroup(c.Int("id"), c.IntSlice("device")...)
+	checkErr(err)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	tradfri "github.com/barnybug/go-tradfri"
 	"github.com/barnybug/go-tradfri/log"
@@ -49,14 +50,123 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:  "device",
+			Usage: "manage devices",
+			Subcommands: []cli.Command{
+				{
+					Name:   "rename",
+					Usage:  "rename a device",
+					Action: deviceRenameCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "device id",
+						},
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "new name",
+						},
+					},
+				},
+				{
+					Name:   "remove",
+					Usage:  "remove (unpair) a device from the gateway",
+					Action: deviceRemoveCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "device id",
+						},
+						cli.BoolFlag{
+							Name:  "confirm",
+							Usage: "confirm removal",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:   "groups",
-			Usage:  "scan for groups",
+			Usage:  "scan for groups, or manage them",
 			Action: groupsCommand,
+			Subcommands: []cli.Command{
+				{
+					Name:   "create",
+					Usage:  "create a group",
+					Action: groupCreateCommand,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "group name",
+						},
+						cli.IntSliceFlag{
+							Name:  "device",
+							Usage: "device id (repeatable)",
+						},
+					},
+				},
+				{
+					Name:   "rename",
+					Usage:  "rename a group",
+					Action: groupRenameCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "group id",
+						},
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "group name",
+						},
+					},
+				},
+				{
+					Name:   "delete",
+					Usage:  "delete a group",
+					Action: groupDeleteCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "group id",
+						},
+					},
+				},
+				{
+					Name:   "add",
+					Usage:  "add devices to a group",
+					Action: groupAddCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "group id",
+						},
+						cli.IntSliceFlag{
+							Name:  "device",
+							Usage: "device id (repeatable)",
+						},
+					},
+				},
+				{
+					Name:   "remove",
+					Usage:  "remove devices from a group",
+					Action: groupRemoveCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "group id",
+						},
+						cli.IntSliceFlag{
+							Name:  "device",
+							Usage: "device id (repeatable)",
+						},
+					},
+				},
+			},
 		},
 		{
 			Name:   "set",
-			Usage:  "switch/dim/color a device or group",
+			Usage:  "switch/dim/color a device or group, or switch an outlet",
 			Action: setCommand,
 			Flags: []cli.Flag{
 				cli.IntFlag{
@@ -103,23 +213,293 @@ func main() {
 					Name:  "duration",
 					Usage: "transition duration (ms)",
 				},
+				cli.IntFlag{
+					Name:  "channel",
+					Usage: "light channel of a multi-socket fixture",
+				},
+			},
+		},
+		{
+			Name:   "moods",
+			Usage:  "list the moods of a group, or manage them",
+			Action: moodsCommand,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "group",
+					Usage: "group id",
+				},
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:   "activate",
+					Usage:  "activate a mood",
+					Action: moodActivateCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "group",
+							Usage: "group id",
+						},
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "mood id",
+						},
+					},
+				},
+				{
+					Name:   "create",
+					Usage:  "create a mood from the current state of the group",
+					Action: moodCreateCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "group",
+							Usage: "group id",
+						},
+						cli.StringFlag{
+							Name:  "name",
+							Usage: "mood name",
+						},
+					},
+				},
+				{
+					Name:   "delete",
+					Usage:  "delete a mood",
+					Action: moodDeleteCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "group",
+							Usage: "group id",
+						},
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "mood id",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "tasks",
+			Usage:  "list smart tasks, or manage them",
+			Action: tasksCommand,
+			Subcommands: []cli.Command{
+				{
+					Name:   "create",
+					Usage:  "create a smart task",
+					Action: taskCreateCommand,
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "type",
+							Usage: "wakeup, onoff or notathome",
+						},
+					}, taskFlags...),
+				},
+				{
+					Name:   "update",
+					Usage:  "change a smart task",
+					Action: taskUpdateCommand,
+					Flags: append([]cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "smart task id",
+						},
+					}, taskFlags...),
+				},
+				{
+					Name:   "enable",
+					Usage:  "enable a smart task",
+					Action: taskEnableCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "smart task id",
+						},
+					},
+				},
+				{
+					Name:   "disable",
+					Usage:  "disable a smart task",
+					Action: taskDisableCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "smart task id",
+						},
+					},
+				},
+				{
+					Name:   "delete",
+					Usage:  "delete a smart task",
+					Action: taskDeleteCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "smart task id",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "notifications",
+			Usage:  "list gateway notifications",
+			Action: notificationsCommand,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "follow, f",
+					Usage: "wait for and print new notifications",
+				},
+			},
+		},
+		{
+			Name:   "blind",
+			Usage:  "move or stop a blind",
+			Action: blindCommand,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "id",
+					Usage: "device id",
+				},
+				cli.IntFlag{
+					Name:  "position",
+					Usage: "position (0 open - 100 closed)",
+				},
+				cli.BoolFlag{
+					Name:  "stop",
+					Usage: "stop moving",
+				},
+			},
+		},
+		{
+			Name:  "purifier",
+			Usage: "show or control air purifiers",
+			Subcommands: []cli.Command{
+				{
+					Name:   "show",
+					Usage:  "show air purifier state",
+					Action: purifierShowCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "device id (default all)",
+						},
+					},
+				},
+				{
+					Name:   "set",
+					Usage:  "change air purifier settings",
+					Action: purifierSetCommand,
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "id",
+							Usage: "device id",
+						},
+						cli.StringFlag{
+							Name:  "mode",
+							Usage: "fan mode (off, auto)",
+						},
+						cli.IntFlag{
+							Name:  "speed",
+							Usage: "manual fan speed (2-50)",
+						},
+						cli.StringFlag{
+							Name:  "lock",
+							Usage: "child lock (on, off)",
+						},
+						cli.StringFlag{
+							Name:  "leds",
+							Usage: "status LEDs (on, off)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "pair",
+			Usage:  "put the gateway into pairing mode and report new devices",
+			Action: pairCommand,
+			Flags: []cli.Flag{
+				cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "how long to wait for new devices",
+					Value: 60 * time.Second,
+				},
 			},
 		},
 		{
 			Name:   "info",
 			Usage:  "get gateway info",
 			Action: infoCommand,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "verbose, v",
+					Usage: "show all gateway settings",
+				},
+			},
+		},
+		{
+			Name:  "gateway",
+			Usage: "change gateway settings",
+			Subcommands: []cli.Command{
+				{
+					Name:   "set",
+					Usage:  "change gateway settings",
+					Action: gatewaySetCommand,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "ntp",
+							Usage: "NTP server",
+						},
+						cli.StringFlag{
+							Name:  "timezone",
+							Usage: "time zone, e.g. Europe/London; re-run each year to update the daylight saving dates",
+						},
+					},
+				},
+			},
 		},
 		{
 			Name:   "reboot",
 			Usage:  "reboot gateway",
 			Action: rebootCommand,
 		},
-		// {
-		// 	Name:   "watch",
-		// 	Usage:  "watch for events",
-		// 	Action: watchCommand,
-		// },
+		{
+			Name:   "firmware",
+			Usage:  "show firmware versions and update states",
+			Action: firmwareCommand,
+			Subcommands: []cli.Command{
+				{
+					Name:   "check",
+					Usage:  "ask the gateway to check for updates",
+					Action: firmwareCheckCommand,
+				},
+				{
+					Name:   "install",
+					Usage:  "install a downloaded gateway update",
+					Action: firmwareInstallCommand,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "confirm",
+							Usage: "confirm the gateway reboot",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "watch",
+			Usage:  "watch devices and groups for changes",
+			Action: watchCommand,
+			Flags: []cli.Flag{
+				cli.IntSliceFlag{
+					Name:  "id",
+					Usage: "device or group id (repeatable, default all)",
+				},
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "output one JSON object per line",
+				},
+			},
+		},
 		{
 			Name:   "factory_reset",
 			Usage:  "factory reset the gateway",
@@ -174,6 +554,33 @@ func devicesCommand(c *cli.Context) error {
 	return nil
 }
 
+func deviceRenameCommand(c *cli.Context) error {
+	if !c.IsSet("id") || c.String("name") == "" {
+		return errors.New("required arguments: --id --name")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.RenameDevice(c.Int("id"), c.String("name"))
+	checkErr(err)
+	return nil
+}
+
+func deviceRemoveCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	if !c.Bool("confirm") {
+		return errors.New("the device must be paired again to be used: pass --confirm to remove it")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.RemoveDevice(c.Int("id"))
+	checkErr(err)
+	return nil
+}
+
 func setCommand(c *cli.Context) error {
 	power := 1
 	if c.BoolT("off") {
@@ -233,10 +640,42 @@ func setCommand(c *cli.Context) error {
 	client, err := connect(c)
 	checkErr(err)
 	id := c.Int("id")
-	if id&(1<<17) == 0 {
+	if id&(1<<17) != 0 {
+		err = client.SetGroup(id, change)
+		checkErr(err)
+		return nil
+	}
+	device, err := client.GetDeviceDescription(id)
+	checkErr(err)
+	if device.ApplicationType == tradfri.Plug {
+		for _, flag := range []string{"level", "temp", "tempascolor", "color", "colorX", "colorY", "hue", "sat", "duration", "channel"} {
+			if c.IsSet(flag) {
+				return fmt.Errorf("--%s is not supported by plugs", flag)
+			}
+		}
+		err = client.SetPlug(id, power == 1)
+	} else if c.IsSet("channel") {
+		err = client.SetDeviceChannel(id, c.Int("channel"), change)
+	} else {
 		err = client.SetDevice(id, change)
+	}
+	checkErr(err)
+	return nil
+}
+
+func blindCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	if c.IsSet("position") == c.Bool("stop") {
+		return errors.New("required arguments: --position or --stop")
+	}
+	client, err := connect(c)
+	checkErr(err)
+	if c.Bool("stop") {
+		err = client.StopBlind(c.Int("id"))
 	} else {
-		err = client.SetGroup(id, change)
+		err = client.SetBlindPosition(c.Int("id"), c.Int("position"))
 	}
 	checkErr(err)
 	return nil
@@ -255,24 +694,24 @@ func groupsCommand(c *cli.Context) error {
 	return nil
 }
 
-// func watchCommand(c *cli.Context) error {
-// 	client, err := connect(c)
-// 	checkErr(err)
-
-// 	fmt.Println("Listing devices...")
-// 	deviceIds, err := client.ListDeviceIds()
-// 	checkErr(err)
-
-// 	fmt.Printf("Watching %d devices...\n", len(deviceIds))
-// 	for _, id := range deviceIds {
-// 		client.Observe(id)
-// 	}
+func pairCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
 
-// 	for msg := range client.Events() {
-// 		fmt.Printf("%s\n", msg)
-// 	}
-// 	return nil
-// }
+	timeout := c.Duration("timeout")
+	fmt.Printf("Pairing for %s: reset the new device or hold its pairing button near the gateway\n", timeout)
+	added, err := client.Commission(timeout, func(id int) {
+		device, err := client.GetDeviceDescription(id)
+		if err != nil {
+			fmt.Printf("Paired device %d\n", id)
+			return
+		}
+		fmt.Printf("Paired device %d: %s %q\n", id, device.ApplicationType, device.Device.ModelNumber)
+	})
+	checkErr(err)
+	fmt.Printf("Found %d new devices\n", len(added))
+	return nil
+}
 
 func infoCommand(c *cli.Context) error {
 	client, err := connect(c)
@@ -281,7 +720,37 @@ func infoCommand(c *cli.Context) error {
 	info, err := client.GetGatewayInfo()
 	checkErr(err)
 
-	fmt.Printf("%s\n", info)
+	if c.Bool("verbose") {
+		fmt.Println(info.Details())
+	} else {
+		fmt.Printf("%s\n", info)
+	}
+	return nil
+}
+
+func gatewaySetCommand(c *cli.Context) error {
+	if !c.IsSet("ntp") && !c.IsSet("timezone") {
+		return errors.New("required arguments: --ntp or --timezone")
+	}
+	var loc *time.Location
+	if c.IsSet("timezone") {
+		var err error
+		loc, err = time.LoadLocation(c.String("timezone"))
+		if err != nil {
+			return err
+		}
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	if c.IsSet("ntp") {
+		err = client.SetNTPServer(c.String("ntp"))
+		checkErr(err)
+	}
+	if loc != nil {
+		err = client.SetTimezone(loc)
+		checkErr(err)
+	}
 	return nil
 }
 
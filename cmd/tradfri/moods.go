@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+func moodsCommand(c *cli.Context) error {
+	if !c.IsSet("group") {
+		return errors.New("required arguments: --group")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	moods, err := client.ListMoods(c.Int("group"))
+	checkErr(err)
+	for _, mood := range moods {
+		fmt.Println(mood)
+	}
+	return nil
+}
+
+func moodActivateCommand(c *cli.Context) error {
+	if !c.IsSet("group") || !c.IsSet("id") {
+		return errors.New("required arguments: --group --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.ActivateMood(c.Int("group"), c.Int("id"))
+	checkErr(err)
+	return nil
+}
+
+func moodCreateCommand(c *cli.Context) error {
+	if !c.IsSet("group") || c.String("name") == "" {
+		return errors.New("required arguments: --group --name")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	id, err := client.CreateMood(c.Int("group"), c.String("name"))
+	checkErr(err)
+	fmt.Printf("Created mood %d\n", id)
+	return nil
+}
+
+func moodDeleteCommand(c *cli.Context) error {
+	if !c.IsSet("group") || !c.IsSet("id") {
+		return errors.New("required arguments: --group --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.DeleteMood(c.Int("group"), c.Int("id"))
+	checkErr(err)
+	return nil
+}
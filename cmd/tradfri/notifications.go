@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/urfave/cli"
+)
+
+func notificationsCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	notifications, err := client.ListNotifications()
+	checkErr(err)
+	for _, n := range notifications {
+		fmt.Println(n)
+	}
+	if !c.Bool("follow") {
+		return nil
+	}
+
+	checkErr(client.ObserveNotifications())
+	for event := range client.Events() {
+		if n, ok := event.(*tradfri.Notification); ok {
+			fmt.Println(n)
+		}
+	}
+	return nil
+}
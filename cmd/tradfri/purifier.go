@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/urfave/cli"
+)
+
+func purifierShowCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	if c.IsSet("id") {
+		device, err := client.GetDeviceDescription(c.Int("id"))
+		checkErr(err)
+		fmt.Println(device)
+		return nil
+	}
+
+	devices, err := client.ListDevices()
+	checkErr(err)
+	for _, device := range devices {
+		if device.ApplicationType == tradfri.AirPurifier {
+			fmt.Println(device)
+		}
+	}
+	return nil
+}
+
+func purifierSetCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	id := c.Int("id")
+	// flags are parsed before connecting, then applied with the client's
+	// setters, which validate them
+	var sets []func(*tradfri.Client) error
+	if c.IsSet("mode") {
+		var mode int
+		switch c.String("mode") {
+		case "off":
+			mode = tradfri.FanModeOff
+		case "auto":
+			mode = tradfri.FanModeAuto
+		default:
+			return errors.New("--mode must be off or auto")
+		}
+		sets = append(sets, func(client *tradfri.Client) error { return client.SetAirPurifierMode(id, mode) })
+	}
+	if c.IsSet("speed") {
+		speed := c.Int("speed")
+		sets = append(sets, func(client *tradfri.Client) error { return client.SetAirPurifierSpeed(id, speed) })
+	}
+	if c.IsSet("lock") {
+		lock, err := parseOnOff("lock", c.String("lock"))
+		if err != nil {
+			return err
+		}
+		sets = append(sets, func(client *tradfri.Client) error { return client.SetAirPurifierChildLock(id, lock) })
+	}
+	if c.IsSet("leds") {
+		on, err := parseOnOff("leds", c.String("leds"))
+		if err != nil {
+			return err
+		}
+		sets = append(sets, func(client *tradfri.Client) error { return client.SetAirPurifierLEDs(id, on) })
+	}
+	if len(sets) == 0 {
+		return errors.New("required arguments: --mode, --speed, --lock or --leds")
+	}
+
+	client, err := connect(c)
+	checkErr(err)
+	for _, set := range sets {
+		checkErr(set(client))
+	}
+	return nil
+}
+
+func parseOnOff(flag, value string) (bool, error) {
+	switch value {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("--%s must be on or off", flag)
+}
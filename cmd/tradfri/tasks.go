@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/urfave/cli"
+)
+
+var taskTypes = map[string]tradfri.SmartTaskType{
+	"notathome": tradfri.TaskNotAtHome,
+	"onoff":     tradfri.TaskOnOff,
+	"wakeup":    tradfri.TaskWakeUp,
+}
+
+var taskFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "days",
+		Usage: "repeat days: mon,tue,... or daily, weekdays, weekend",
+	},
+	cli.StringFlag{
+		Name:  "start",
+		Usage: "start time (HH:MM, UTC)",
+	},
+	cli.StringFlag{
+		Name:  "end",
+		Usage: "end time for notathome tasks (HH:MM, UTC)",
+	},
+	cli.IntSliceFlag{
+		Name:  "device",
+		Usage: "device id (repeatable)",
+	},
+	cli.IntFlag{
+		Name:  "level",
+		Usage: "dim level (0-100)",
+	},
+	cli.IntFlag{
+		Name:  "duration",
+		Usage: "transition duration (ms)",
+	},
+	cli.BoolFlag{
+		Name:  "off",
+		Usage: "switch off rather than on",
+	},
+}
+
+func tasksCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	tasks, err := client.ListSmartTasks()
+	checkErr(err)
+	for _, task := range tasks {
+		fmt.Println(task)
+	}
+	return nil
+}
+
+func taskCreateCommand(c *cli.Context) error {
+	taskType, ok := taskTypes[c.String("type")]
+	if !ok {
+		return errors.New("required arguments: --type (wakeup, onoff or notathome)")
+	}
+	if !c.IsSet("start") {
+		return errors.New("required arguments: --start")
+	}
+	task := tradfri.SmartTask{
+		Enabled:    1,
+		Type:       taskType,
+		RepeatDays: tradfri.EveryDay,
+		StartAction: tradfri.StartAction{
+			Power: 1,
+		},
+		Intervals: []tradfri.TimeInterval{{}},
+	}
+	if err := applyTaskFlags(c, &task); err != nil {
+		return err
+	}
+
+	client, err := connect(c)
+	checkErr(err)
+	id, err := client.CreateSmartTask(task)
+	checkErr(err)
+	fmt.Printf("Created smart task %d\n", id)
+	return nil
+}
+
+func taskUpdateCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	task, err := client.GetSmartTask(c.Int("id"))
+	checkErr(err)
+	if len(task.Intervals) == 0 {
+		task.Intervals = []tradfri.TimeInterval{{}}
+	}
+	if err := applyTaskFlags(c, task); err != nil {
+		return err
+	}
+	err = client.UpdateSmartTask(*task)
+	checkErr(err)
+	return nil
+}
+
+// applyTaskFlags updates task from the flags given.
+func applyTaskFlags(c *cli.Context, task *tradfri.SmartTask) error {
+	if c.IsSet("days") {
+		days, err := tradfri.ParseWeekdays(c.String("days"))
+		if err != nil {
+			return err
+		}
+		task.RepeatDays = days
+	}
+	interval := &task.Intervals[0]
+	if c.IsSet("start") {
+		t, err := time.Parse("15:04", c.String("start"))
+		if err != nil {
+			return fmt.Errorf("--start: %v", err)
+		}
+		interval.StartHour, interval.StartMinute = t.Hour(), t.Minute()
+	}
+	if c.IsSet("end") {
+		t, err := time.Parse("15:04", c.String("end"))
+		if err != nil {
+			return fmt.Errorf("--end: %v", err)
+		}
+		hour, minute := t.Hour(), t.Minute()
+		interval.EndHour, interval.EndMinute = &hour, &minute
+	}
+	if c.IsSet("off") {
+		task.StartAction.Power = 0
+	}
+	if c.IsSet("device") {
+		task.StartAction.LightSettings = nil
+		for _, id := range c.IntSlice("device") {
+			task.StartAction.LightSettings = append(task.StartAction.LightSettings, tradfri.LightSetting{DeviceID: id})
+		}
+	}
+	for i := range task.StartAction.LightSettings {
+		ls := &task.StartAction.LightSettings[i]
+		if c.IsSet("level") {
+			dim := tradfri.PercentageToDim(c.Int("level"))
+			ls.Dim = &dim
+		}
+		if c.IsSet("duration") {
+			d := tradfri.MsToDuration(c.Int("duration"))
+			ls.Duration = &d
+		}
+	}
+	return nil
+}
+
+func taskEnableCommand(c *cli.Context) error {
+	return setTaskEnabled(c, true)
+}
+
+func taskDisableCommand(c *cli.Context) error {
+	return setTaskEnabled(c, false)
+}
+
+func setTaskEnabled(c *cli.Context, enabled bool) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.EnableSmartTask(c.Int("id"), enabled)
+	checkErr(err)
+	return nil
+}
+
+func taskDeleteCommand(c *cli.Context) error {
+	if !c.IsSet("id") {
+		return errors.New("required arguments: --id")
+	}
+	client, err := connect(c)
+	checkErr(err)
+
+	err = client.DeleteSmartTask(c.Int("id"))
+	checkErr(err)
+	return nil
+}
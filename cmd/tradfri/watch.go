@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/urfave/cli"
+)
+
+type change struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+}
+
+type watchEvent struct {
+	Time    time.Time          `json:"time"`
+	Kind    string             `json:"kind"`
+	ID      int                `json:"id"`
+	Name    string             `json:"name"`
+	Changes map[string]*change `json:"changes"`
+}
+
+func isGroup(id int) bool {
+	return id&(1<<17) != 0
+}
+
+func powerString(power int) string {
+	if power != 0 {
+		return "on"
+	}
+	return "off"
+}
+
+func colorString(lc tradfri.LightControl) string {
+	switch {
+	case lc.Mireds != nil:
+		return fmt.Sprintf("%dK", tradfri.MiredToKelvin(*lc.Mireds))
+	case lc.Color != nil:
+		return "#" + *lc.Color
+	case lc.ColorX != nil && lc.ColorY != nil:
+		return fmt.Sprintf("X:%d/Y:%d", *lc.ColorX, *lc.ColorY)
+	}
+	return ""
+}
+
+func deviceFields(d *tradfri.DeviceDescription) map[string]string {
+	fields := map[string]string{
+		"reachable": fmt.Sprint(d.ReachabilityState != 0),
+	}
+	if len(d.LightControl) > 0 {
+		lc := d.LightControl[0]
+		if lc.Power != nil {
+			fields["power"] = powerString(*lc.Power)
+		}
+		if lc.Dim != nil {
+			fields["dim"] = fmt.Sprintf("%d%%", tradfri.DimToPercentage(*lc.Dim))
+		}
+		if color := colorString(lc); color != "" {
+			fields["color"] = color
+		}
+	}
+	if len(d.PlugControl) > 0 && d.PlugControl[0].Power != nil {
+		fields["power"] = powerString(*d.PlugControl[0].Power)
+	}
+	return fields
+}
+
+func groupFields(g *tradfri.GroupDescription) map[string]string {
+	return map[string]string{
+		"power": powerString(g.Power),
+		"dim":   fmt.Sprintf("%d%%", tradfri.DimToPercentage(g.Dim)),
+	}
+}
+
+// diff returns the fields that differ between prev and next. prev is nil on
+// the first update, in which case all fields are reported.
+func diff(prev, next map[string]string) map[string]*change {
+	changes := map[string]*change{}
+	for k, v := range next {
+		if prev == nil {
+			changes[k] = &change{To: v}
+		} else if old := prev[k]; old != v {
+			changes[k] = &change{From: old, To: v}
+		}
+	}
+	return changes
+}
+
+func (e *watchEvent) String() string {
+	var keys []string
+	for k := range e.Changes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{fmt.Sprintf("%s %s %d %q:", e.Time.Format("15:04:05"), e.Kind, e.ID, e.Name)}
+	for _, k := range keys {
+		c := e.Changes[k]
+		if c.From == "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, c.To))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s=%s->%s", k, c.From, c.To))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func watchCommand(c *cli.Context) error {
+	client, err := connect(c)
+	checkErr(err)
+
+	var deviceIds, groupIds []int
+	if c.IsSet("id") {
+		for _, id := range c.IntSlice("id") {
+			if isGroup(id) {
+				groupIds = append(groupIds, id)
+			} else {
+				deviceIds = append(deviceIds, id)
+			}
+		}
+	} else {
+		deviceIds, err = client.ListDeviceIds()
+		checkErr(err)
+		groupIds, err = client.ListGroupIds()
+		checkErr(err)
+	}
+
+	for _, id := range deviceIds {
+		checkErr(client.ObserveDevice(id))
+	}
+	for _, id := range groupIds {
+		checkErr(client.ObserveGroup(id))
+	}
+	if !c.Bool("json") {
+		fmt.Printf("Watching %d devices and %d groups...\n", len(deviceIds), len(groupIds))
+	}
+
+	state := map[int]map[string]string{}
+	enc := json.NewEncoder(os.Stdout)
+	for msg := range client.Events() {
+		var event watchEvent
+		var fields map[string]string
+		switch v := msg.(type) {
+		case *tradfri.DeviceDescription:
+			event = watchEvent{Kind: "device", ID: v.DeviceID, Name: v.DeviceName}
+			fields = deviceFields(v)
+		case *tradfri.GroupDescription:
+			event = watchEvent{Kind: "group", ID: v.GroupID, Name: v.GroupName}
+			fields = groupFields(v)
+		default:
+			continue
+		}
+		event.Time = time.Now()
+		event.Changes = diff(state[event.ID], fields)
+		state[event.ID] = fields
+		if len(event.Changes) == 0 {
+			continue
+		}
+
+		if c.Bool("json") {
+			checkErr(enc.Encode(event))
+		} else {
+			fmt.Println(event.String())
+		}
+	}
+	return nil
+}
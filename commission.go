@@ -0,0 +1,100 @@
+package tradfri
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/barnybug/go-tradfri/log"
+)
+
+// maxCommissionPoll is the longest interval between checks for new devices
+// while commissioning.
+const maxCommissionPoll = 2 * time.Second
+
+// StartCommissioning puts the gateway into commissioning mode for timeout,
+// so new devices can be paired with it.
+func (c *Client) StartCommissioning(timeout time.Duration) error {
+	return c.StartCommissioningContext(context.Background(), timeout)
+}
+
+func (c *Client) StartCommissioningContext(ctx context.Context, timeout time.Duration) error {
+	if timeout < time.Second {
+		return fmt.Errorf("Commissioning timeout must be at least 1s, got %s", timeout)
+	}
+	return c.putRequest(ctx, uriGatewayInfo, CommissioningSet{int(timeout / time.Second)})
+}
+
+// StopCommissioning ends commissioning mode.
+func (c *Client) StopCommissioning() error {
+	return c.StopCommissioningContext(context.Background())
+}
+
+func (c *Client) StopCommissioningContext(ctx context.Context) error {
+	return c.putRequest(ctx, uriGatewayInfo, CommissioningSet{0})
+}
+
+// Commission puts the gateway into commissioning mode for timeout and waits
+// for devices to be paired, calling found (if not nil) with the id of each as
+// it appears. It returns the ids of all new devices.
+func (c *Client) Commission(timeout time.Duration, found func(deviceId int)) ([]int, error) {
+	return c.CommissionContext(context.Background(), timeout, found)
+}
+
+func (c *Client) CommissionContext(ctx context.Context, timeout time.Duration, found func(deviceId int)) ([]int, error) {
+	known := map[int]bool{}
+	ids, err := c.ListDeviceIdsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		known[id] = true
+	}
+	if err := c.StartCommissioningContext(ctx, timeout); err != nil {
+		return nil, err
+	}
+
+	interval := timeout / 10
+	if interval > maxCommissionPoll {
+		interval = maxCommissionPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	var added []int
+	poll := func(ctx context.Context) error {
+		ids, err := c.ListDeviceIdsContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if known[id] {
+				continue
+			}
+			known[id] = true
+			added = append(added, id)
+			if found != nil {
+				found(id)
+			}
+		}
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			// leave commissioning mode, then pick up any device paired since
+			// the last poll
+			if err := c.StopCommissioningContext(context.Background()); err != nil {
+				log.Printf("Unable to stop commissioning: %s", err)
+			}
+			poll(context.Background())
+			return added, ctx.Err()
+		case <-deadline:
+			return added, poll(ctx)
+		case <-ticker.C:
+			if err := poll(ctx); err != nil {
+				return added, err
+			}
+		}
+	}
+}
@@ -2,13 +2,24 @@ package tradfri
 
 // Derived from https://github.com/eclipse/smarthome/blob/4204ce06bb28c28e5f711e720f87ef83beff2e27/extensions/binding/org.eclipse.smarthome.binding.tradfri/src/main/java/org/eclipse/smarthome/binding/tradfri/TradfriBindingConstants.java
 
-const Remote = 0
-const Remote2 = 1
-const Lamp = 2
+// Application types reported by devices.
+const (
+	Remote          ApplicationType = 0
+	Remote2         ApplicationType = 1
+	Lamp            ApplicationType = 2
+	Plug            ApplicationType = 3
+	MotionSensor    ApplicationType = 4
+	SignalRepeater  ApplicationType = 6
+	Blind           ApplicationType = 7
+	SoundController ApplicationType = 8
+	AirPurifier     ApplicationType = 10
+)
+
 const DimMax = 254
 const DimMin = 0
 const MiredMin = 250 // 4000K
 const MiredMax = 454 // 2200K
+
 const ColorTempColdX = 24841
 const ColorTempColdY = 24593
 const ColorTempDayX = 29969
@@ -19,13 +30,27 @@ const ColorTempCold = "f5faf6"
 const ColorTempDay = "f1e0b5"
 const ColorTempWarm = "efd275"
 
+// Air purifier fan modes and speeds. The gateway reports modes 10-50 for
+// manual speeds.
+const FanModeOff = 0
+const FanModeAuto = 1
+const FanSpeedMin = 2
+const FanSpeedMax = 50
+
+// AirQualityUnknown is reported for PM2.5 while the sensor warms up.
+const AirQualityUnknown = 65535
+
 const tradfriPort = 5684
 const preauthIdentity = "Client_identity"
 const (
 	uriDevices             = "/15001"
 	uriGroups              = "/15004"
+	uriMoods               = "/15005"
+	uriNotifications       = "/15006"
+	uriSmartTasks          = "/15010"
 	uriIdent               = "/15011/9063"
 	uriGatewayInfo         = "/15011/15012"
 	uriGatewayReboot       = "/15011/9030"
 	uriGatewayFactoryReset = "/15011/9031"
+	uriGatewayUpdate       = "/15011/9034"
 )
@@ -1,17 +1,20 @@
 package tradfri
 
 import (
+	"sync"
 	"time"
 
 	"github.com/barnybug/go-tradfri/log"
-	"github.com/dustin/go-coap"
 	"github.com/eriklupander/dtls"
 )
 
-// DtlsClient provides an domain-agnostic CoAP-client with DTLS transport.
+// DtlsClient provides an domain-agnostic CoAP-client with DTLS transport. It
+// is safe for concurrent use: responses are matched to requests by message ID
+// and token.
 type DtlsClient struct {
+	*endpoint
+	listener       *dtls.Listener
 	peer           *dtls.Peer
-	msgID          uint16
 	gatewayAddress string
 	clientID       string
 	psk            string
@@ -25,13 +28,17 @@ func NewDtlsClient(gatewayAddress, clientID, psk string) (*DtlsClient, error) {
 		psk:            psk,
 	}
 	err := client.connect()
+	if err == nil {
+		client.endpoint = newEndpoint(client.peer)
+	}
 	return client, err
 }
 
 func (dc *DtlsClient) connect() error {
 	dc.setupKeystore()
 
-	listener, err := dtls.NewUdpListener(":0", time.Second*900)
+	var err error
+	dc.listener, err = dtls.NewUdpListener(":0", time.Second*900)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -42,9 +49,10 @@ func (dc *DtlsClient) connect() error {
 		HandshakeTimeout: time.Second * 15}
 	log.Printf("Connecting to peer at %v\n", dc.gatewayAddress)
 
-	dc.peer, err = listener.AddPeerWithParams(peerParams)
+	dc.peer, err = dc.listener.AddPeerWithParams(peerParams)
 	if err != nil {
 		log.Errorf("Unable to connect to Gateway at %v: %v\n", dc.gatewayAddress, err.Error())
+		dc.listener.Shutdown()
 		return err
 	}
 	dc.peer.UseQueue(true)
@@ -52,85 +60,38 @@ func (dc *DtlsClient) connect() error {
 	return nil
 }
 
-// Call writes the supplied coap.Message to the peer
-func (dc *DtlsClient) Call(req coap.Message) (coap.Message, error) {
-	log.Printf("Calling %v %v", req.Code.String(), req.PathString())
-	data, err := req.MarshalBinary()
-	if err != nil {
-		return coap.Message{}, err
-	}
-	err = dc.peer.Write(data)
-
-	if err != nil {
-		return coap.Message{}, err
-	}
-
-	respData, err := dc.peer.Read(time.Second)
-	if err != nil {
-		return coap.Message{}, err
-	}
-
-	msg, err := coap.ParseMessage(respData)
-	if err != nil {
-		return coap.Message{}, err
-	}
-
-	log.Printf("MessageID: %v\n", msg.MessageID)
-	log.Printf("Type: %v\n", msg.Type)
-	log.Printf("Code: %v\n", msg.Code)
-	log.Printf("Token: %v\n", msg.Token)
-	log.Printf("Payload: %v\n", string(msg.Payload))
-
-	return msg, nil
-}
-
-// BuildGETMessage produces a CoAP GET message with the next msgID set.
-func (dc *DtlsClient) BuildGETMessage(path string) coap.Message {
-	dc.msgID++
-	req := coap.Message{
-		Type:      coap.Confirmable,
-		Code:      coap.GET,
-		MessageID: dc.msgID,
+// Close notifies the gateway that the session is over, then releases the
+// socket and stops the receiver, which then closes all observation channels.
+// The DtlsClient cannot be used afterwards.
+func (dc *DtlsClient) Close() error {
+	dc.endpoint.close()
+	if dc.peer != nil {
+		dc.listener.RemovePeer(dc.peer, dtls.AlertDesc_CloseNotify)
 	}
-	req.SetPathString(path)
-	return req
+	return dc.listener.Shutdown()
 }
 
-//req.SetOption(coap.ETag, "weetag")
-//req.SetOption(coap.MaxAge, 3)
-
-// BuildPUTMessage produces a CoAP PUT message with the next msgID set.
-func (dc *DtlsClient) BuildPUTMessage(path string, payload string) coap.Message {
-	dc.msgID++
+// keystore holds the PSKs of every DtlsClient, as the dtls package only
+// supports a process-wide set of keystores.
+var keystore = &sharedKeystore{keys: map[string][]byte{}}
+var keystoreOnce sync.Once
 
-	req := coap.Message{
-		Type:      coap.Confirmable,
-		Code:      coap.PUT,
-		MessageID: dc.msgID,
-		Payload:   []byte(payload),
-	}
-	req.SetPathString(path)
-
-	return req
+type sharedKeystore struct {
+	mu   sync.Mutex
+	keys map[string][]byte
 }
 
-// BuildPOSTMessage produces a CoAP POST message with the next msgID set.
-func (dc *DtlsClient) BuildPOSTMessage(path string, payload string) coap.Message {
-	dc.msgID++
-
-	req := coap.Message{
-		Type:      coap.Confirmable,
-		Code:      coap.POST,
-		MessageID: dc.msgID,
-		Payload:   []byte(payload),
-	}
-	req.SetPathString(path)
-
-	return req
+func (ks *sharedKeystore) GetPsk(identity string, remoteAddr string) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.keys[identity], nil
 }
 
 func (dc *DtlsClient) setupKeystore() {
-	mks := dtls.NewKeystoreInMemory()
-	dtls.SetKeyStores([]dtls.Keystore{mks})
-	mks.AddKey(dc.clientID, []byte(dc.psk))
+	keystoreOnce.Do(func() {
+		dtls.SetKeyStores([]dtls.Keystore{keystore})
+	})
+	keystore.mu.Lock()
+	keystore.keys[dc.clientID] = []byte(dc.psk)
+	keystore.mu.Unlock()
 }
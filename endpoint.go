@@ -0,0 +1,423 @@
+package tradfri
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/barnybug/go-tradfri/log"
+	"github.com/dustin/go-coap"
+)
+
+// packetConn is a datagram connection to a single peer.
+type packetConn interface {
+	Read(timeout time.Duration) ([]byte, error)
+	Write(data []byte) error
+}
+
+// endpoint is a CoAP client over a packetConn. It is safe for concurrent use:
+// responses are matched to requests by message ID and token.
+type endpoint struct {
+	conn  packetConn
+	msgID uint32
+
+	// Params control retransmission of confirmable requests. They may be
+	// changed before the first call.
+	Params TransmissionParams
+
+	writeMu   sync.Mutex // the connection is not safe for concurrent writes
+	mu        sync.Mutex // guards pending, tokens and observers
+	pending   map[uint16]*exchange
+	tokens    map[string]*exchange
+	observers map[string]chan coap.Message
+	seen      map[uint16]seenMessage // only accessed by receive
+	done      chan struct{}
+}
+
+// newEndpoint starts a CoAP client over conn.
+func newEndpoint(conn packetConn) *endpoint {
+	e := &endpoint{
+		conn:      conn,
+		Params:    DefaultTransmissionParams,
+		msgID:     randomMessageID(),
+		pending:   map[uint16]*exchange{},
+		tokens:    map[string]*exchange{},
+		observers: map[string]chan coap.Message{},
+		seen:      map[uint16]seenMessage{},
+		done:      make(chan struct{}),
+	}
+	go e.receive()
+	return e
+}
+
+// Do sends a request for path with the given method and payload, and waits
+// for the response until ctx is done.
+func (e *endpoint) Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error) {
+	return e.CallContext(ctx, e.buildMessage(method, path, payload))
+}
+
+// close stops the receiver, which then closes all observation channels.
+func (e *endpoint) close() {
+	close(e.done)
+}
+
+// exchange is an outstanding request. It is registered by message ID until
+// acknowledged and by token until a response arrives, which may be
+// piggybacked on the ACK or sent separately.
+type exchange struct {
+	token    []byte
+	acked    chan struct{} // closed on an empty ACK
+	response chan coap.Message
+}
+
+func newExchange(token []byte) *exchange {
+	return &exchange{
+		token:    token,
+		acked:    make(chan struct{}),
+		response: make(chan coap.Message, 1),
+	}
+}
+
+// seenMessage records how a message from the peer was answered, so that
+// duplicates can be answered the same way without being processed again.
+type seenMessage struct {
+	at    time.Time
+	reply coap.COAPType
+}
+
+// Call writes the supplied coap.Message to the peer and waits for the matching
+// response. A token is assigned if the message has none.
+func (e *endpoint) Call(req coap.Message) (coap.Message, error) {
+	return e.CallContext(context.Background(), req)
+}
+
+// CallContext is like Call but waits for the response until ctx is done.
+// Confirmable requests are retransmitted with exponential backoff until
+// acknowledged. If ctx has no deadline, the wait is limited to the
+// MaxTransmitWait of the client's Params.
+func (e *endpoint) CallContext(ctx context.Context, req coap.Message) (coap.Message, error) {
+	if len(req.Token) == 0 {
+		req.Token = newToken()
+	}
+	ex := newExchange(req.Token)
+	e.mu.Lock()
+	e.pending[req.MessageID] = ex
+	e.tokens[string(req.Token)] = ex
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, req.MessageID)
+		delete(e.tokens, string(req.Token))
+		e.mu.Unlock()
+	}()
+
+	log.Printf("Calling %v %v", req.Code.String(), req.PathString())
+	msg, err := e.transmit(ctx, req, ex)
+	if err != nil {
+		return coap.Message{}, err
+	}
+	if msg.Type == coap.Reset {
+		return coap.Message{}, errors.New("Request reset by peer")
+	}
+	log.Printf("MessageID: %v\n", msg.MessageID)
+	log.Printf("Type: %v\n", msg.Type)
+	log.Printf("Code: %v\n", msg.Code)
+	log.Printf("Token: %v\n", msg.Token)
+	log.Printf("Payload: %v\n", string(msg.Payload))
+	return msg, nil
+}
+
+// transmit writes req, retransmitting it until it is acknowledged, and waits
+// for the response to arrive on ex.
+func (e *endpoint) transmit(ctx context.Context, req coap.Message, ex *exchange) (coap.Message, error) {
+	params := e.Params.withDefaults()
+	var deadline <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		deadline = time.After(params.MaxTransmitWait())
+	}
+
+	err := e.write(req)
+	if err != nil {
+		return coap.Message{}, err
+	}
+	timeout := params.initialTimeout()
+	retransmit := time.NewTimer(timeout)
+	defer retransmit.Stop()
+	retransmitC := retransmit.C
+	acked := ex.acked
+	for attempt := 0; ; {
+		select {
+		case msg := <-ex.response:
+			return msg, nil
+		case <-acked:
+			// the response will be sent separately, stop retransmitting and
+			// ignore any tick the timer has already sent
+			retransmit.Stop()
+			retransmitC = nil
+			acked = nil
+		case <-retransmitC:
+			if attempt == params.MaxRetransmit {
+				return coap.Message{}, ErrTimeout
+			}
+			attempt++
+			timeout *= 2
+			log.Printf("Retransmitting %v (attempt %d)", req.MessageID, attempt)
+			err := e.write(req)
+			if err != nil {
+				return coap.Message{}, err
+			}
+			retransmit.Reset(timeout)
+		case <-deadline:
+			return coap.Message{}, ErrTimeout
+		case <-ctx.Done():
+			return coap.Message{}, ctx.Err()
+		}
+	}
+}
+
+// Ping sends a CoAP ping (an empty confirmable message) and waits for the
+// peer to reject it, confirming the session is still alive.
+func (e *endpoint) Ping() error {
+	req := coap.Message{
+		Type:      coap.Confirmable,
+		MessageID: e.nextMessageID(),
+	}
+	ex := newExchange(nil)
+	e.mu.Lock()
+	e.pending[req.MessageID] = ex
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		delete(e.pending, req.MessageID)
+		e.mu.Unlock()
+	}()
+
+	_, err := e.transmit(context.Background(), req, ex)
+	return err
+}
+
+func (e *endpoint) write(msg coap.Message) error {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	return e.conn.Write(data)
+}
+
+// Observe registers an observation of path with the peer. The initial
+// response and every subsequent notification are delivered on the returned
+// channel.
+func (e *endpoint) Observe(path string) (<-chan coap.Message, error) {
+	return e.ObserveContext(context.Background(), path)
+}
+
+// ObserveContext is like Observe but waits for the registration to be
+// acknowledged until ctx is done.
+func (e *endpoint) ObserveContext(ctx context.Context, path string) (<-chan coap.Message, error) {
+	req := e.BuildGETMessage(path)
+	req.SetOption(coap.Observe, 0)
+	req.Token = newToken()
+
+	ch := make(chan coap.Message, 16)
+	e.mu.Lock()
+	e.observers[string(req.Token)] = ch
+	e.mu.Unlock()
+
+	resp, err := e.CallContext(ctx, req)
+	if err == nil && resp.Option(coap.Observe) == nil {
+		err = errors.New("Resource not observable: " + path)
+	}
+	if err != nil {
+		e.mu.Lock()
+		delete(e.observers, string(req.Token))
+		e.mu.Unlock()
+		return nil, err
+	}
+	e.mu.Lock()
+	if _, ok := e.observers[string(req.Token)]; ok {
+		ch <- resp
+	}
+	e.mu.Unlock()
+	return ch, nil
+}
+
+// receive reads messages from the peer, routing notifications to observers
+// and everything else to the waiting request.
+func (e *endpoint) receive() {
+	defer func() {
+		e.mu.Lock()
+		for token, ch := range e.observers {
+			close(ch)
+			delete(e.observers, token)
+		}
+		e.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+		data, err := e.conn.Read(time.Second)
+		if err != nil {
+			continue
+		}
+		msg, err := coap.ParseMessage(data)
+		if err != nil {
+			log.Printf("Error parsing message: %s", err)
+			continue
+		}
+		e.dispatch(msg)
+	}
+}
+
+func (e *endpoint) dispatch(msg coap.Message) {
+	switch msg.Type {
+	case coap.Acknowledgement, coap.Reset:
+		e.mu.Lock()
+		ex, ok := e.pending[msg.MessageID]
+		delete(e.pending, msg.MessageID)
+		e.mu.Unlock()
+		if !ok {
+			log.Printf("Unexpected acknowledgement: %v", msg.MessageID)
+			return
+		}
+		if msg.Type == coap.Acknowledgement && msg.Code == 0 {
+			// empty ACK: the response will follow separately
+			log.Printf("Awaiting separate response: %v", msg.MessageID)
+			close(ex.acked)
+			return
+		}
+		if msg.Type == coap.Acknowledgement && !bytes.Equal(msg.Token, ex.token) {
+			log.Printf("Token mismatch for %v", msg.MessageID)
+			return
+		}
+		ex.deliver(msg)
+		return
+	}
+
+	if prev, ok := e.seen[msg.MessageID]; ok && time.Since(prev.at) < exchangeLifetime {
+		log.Printf("Duplicate message: %v", msg.MessageID)
+		if msg.IsConfirmable() {
+			e.reply(msg, prev.reply)
+		}
+		return
+	}
+
+	e.mu.Lock()
+	ex, isResponse := e.tokens[string(msg.Token)]
+	if isResponse {
+		delete(e.tokens, string(msg.Token))
+	}
+	ch, isNotification := e.observers[string(msg.Token)]
+	e.mu.Unlock()
+
+	if !isResponse && !isNotification {
+		log.Printf("Rejecting unexpected message: %v", msg.MessageID)
+		e.remember(msg.MessageID, coap.Reset)
+		e.reply(msg, coap.Reset)
+		return
+	}
+	e.remember(msg.MessageID, coap.Acknowledgement)
+	if msg.IsConfirmable() {
+		e.reply(msg, coap.Acknowledgement)
+	}
+	if isResponse {
+		ex.deliver(msg)
+		return
+	}
+	select {
+	case ch <- msg:
+	default:
+		log.Printf("Dropping notification: %v", msg.MessageID)
+	}
+}
+
+// remember records the reply to a message from the peer for duplicate
+// detection, forgetting messages older than the exchange lifetime.
+func (e *endpoint) remember(id uint16, reply coap.COAPType) {
+	now := time.Now()
+	if len(e.seen) >= 256 {
+		for k, v := range e.seen {
+			if now.Sub(v.at) >= exchangeLifetime {
+				delete(e.seen, k)
+			}
+		}
+	}
+	e.seen[id] = seenMessage{at: now, reply: reply}
+}
+
+// reply sends an empty ACK or RST for msg.
+func (e *endpoint) reply(msg coap.Message, typ coap.COAPType) {
+	err := e.write(coap.Message{
+		Type:      typ,
+		MessageID: msg.MessageID,
+	})
+	if err != nil {
+		log.Printf("Error replying to %v: %s", msg.MessageID, err)
+	}
+}
+
+func (ex *exchange) deliver(msg coap.Message) {
+	select {
+	case ex.response <- msg:
+	default:
+	}
+}
+
+func (e *endpoint) nextMessageID() uint16 {
+	return uint16(atomic.AddUint32(&e.msgID, 1))
+}
+
+// BuildGETMessage produces a CoAP GET message with the next msgID set.
+func (e *endpoint) BuildGETMessage(path string) coap.Message {
+	return e.buildMessage(coap.GET, path, nil)
+}
+
+//req.SetOption(coap.ETag, "weetag")
+//req.SetOption(coap.MaxAge, 3)
+
+// BuildPUTMessage produces a CoAP PUT message with the next msgID set.
+func (e *endpoint) BuildPUTMessage(path string, payload string) coap.Message {
+	return e.buildMessage(coap.PUT, path, []byte(payload))
+}
+
+// BuildPOSTMessage produces a CoAP POST message with the next msgID set.
+func (e *endpoint) BuildPOSTMessage(path string, payload string) coap.Message {
+	return e.buildMessage(coap.POST, path, []byte(payload))
+}
+
+// BuildDELETEMessage produces a CoAP DELETE message with the next msgID set.
+func (e *endpoint) BuildDELETEMessage(path string) coap.Message {
+	return e.buildMessage(coap.DELETE, path, nil)
+}
+
+func (e *endpoint) buildMessage(code coap.COAPCode, path string, payload []byte) coap.Message {
+	req := coap.Message{
+		Type:      coap.Confirmable,
+		Code:      code,
+		MessageID: e.nextMessageID(),
+		Payload:   payload,
+	}
+	req.SetPathString(path)
+	return req
+}
+
+func randomMessageID() uint32 {
+	b := make([]byte, 2)
+	rand.Read(b)
+	return uint32(binary.BigEndian.Uint16(b))
+}
+
+func newToken() []byte {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return b
+}
@@ -0,0 +1,110 @@
+package tradfri
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-coap"
+)
+
+var (
+	// ErrTimeout is returned when the peer does not respond to a request in
+	// time.
+	ErrTimeout = errors.New("Timeout waiting for response")
+	// ErrClosed is returned for requests made after Close.
+	ErrClosed = errors.New("Client closed")
+
+	// Errors matching gateway response codes, for use with errors.Is.
+	ErrBadRequest          = errors.New("Bad request")
+	ErrUnauthorized        = errors.New("Unauthorized")
+	ErrForbidden           = errors.New("Forbidden")
+	ErrNotFound            = errors.New("Not found")
+	ErrMethodNotAllowed    = errors.New("Method not allowed")
+	ErrInternalServerError = errors.New("Internal server error")
+	ErrServiceUnavailable  = errors.New("Service unavailable")
+)
+
+var codeErrors = map[coap.COAPCode]error{
+	coap.BadRequest:          ErrBadRequest,
+	coap.Unauthorized:        ErrUnauthorized,
+	coap.Forbidden:           ErrForbidden,
+	coap.NotFound:            ErrNotFound,
+	coap.MethodNotAllowed:    ErrMethodNotAllowed,
+	coap.InternalServerError: ErrInternalServerError,
+	coap.ServiceUnavailable:  ErrServiceUnavailable,
+}
+
+// CoAPError is returned when the gateway responds with a client (4.xx) or
+// server (5.xx) error code.
+type CoAPError struct {
+	Code    coap.COAPCode
+	Payload []byte
+}
+
+func (e *CoAPError) Error() string {
+	s := fmt.Sprintf("CoAP error %d.%02d %s", e.Code>>5, e.Code&0x1f, e.Code)
+	if len(e.Payload) > 0 {
+		s += fmt.Sprintf(": %s", e.Payload)
+	}
+	return s
+}
+
+// Is reports whether target is the sentinel error for e's code, such as
+// ErrNotFound for 4.04.
+func (e *CoAPError) Is(target error) bool {
+	err, ok := codeErrors[e.Code]
+	return ok && err == target
+}
+
+// responseError returns a *CoAPError if resp carries an error code.
+func responseError(resp coap.Message) error {
+	if resp.Code < coap.BadRequest {
+		return nil
+	}
+	return &CoAPError{Code: resp.Code, Payload: resp.Payload}
+}
+
+// DeviceError records the failure of an operation on a single device.
+type DeviceError struct {
+	ID  int
+	Err error
+}
+
+func (e *DeviceError) Error() string {
+	return fmt.Sprintf("device %d: %s", e.ID, e.Err)
+}
+
+func (e *DeviceError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError collects the errors of an operation that continues past
+// individual failures.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	if len(m) == 1 {
+		return m[0].Error()
+	}
+	s := fmt.Sprintf("%d errors:", len(m))
+	for _, err := range m {
+		s += " " + err.Error() + ";"
+	}
+	return strings.TrimSuffix(s, ";")
+}
+
+// Is reports whether any of the errors matches target.
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the collected errors.
+func (m MultiError) Unwrap() []error {
+	return m
+}
@@ -0,0 +1,47 @@
+package tradfri
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/dustin/go-coap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseError(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError(responseError(coap.Message{Code: coap.Content}))
+	assert.NoError(responseError(coap.Message{Code: coap.Changed}))
+
+	err := responseError(coap.Message{Code: coap.NotFound})
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.False(errors.Is(err, ErrBadRequest))
+	assert.Equal("CoAP error 4.04 NotFound", err.Error())
+
+	err = fmt.Errorf("wrapped: %w", responseError(coap.Message{Code: coap.BadRequest, Payload: []byte("bad")}))
+	assert.True(errors.Is(err, ErrBadRequest))
+	var coapErr *CoAPError
+	assert.True(errors.As(err, &coapErr))
+	assert.Equal(coap.BadRequest, coapErr.Code)
+	assert.Equal("CoAP error 4.00 BadRequest: bad", coapErr.Error())
+
+	err = responseError(coap.Message{Code: coap.GatewayTimeout})
+	assert.Equal("CoAP error 5.04 GatewayTimeout", err.Error())
+}
+
+func TestMultiError(t *testing.T) {
+	assert := assert.New(t)
+	notFound := &DeviceError{ID: 65536, Err: responseError(coap.Message{Code: coap.NotFound})}
+	var err error = MultiError{notFound}
+	assert.Equal("device 65536: CoAP error 4.04 NotFound", err.Error())
+	assert.True(errors.Is(err, ErrNotFound))
+	assert.False(errors.Is(err, ErrTimeout))
+
+	err = MultiError{notFound, &DeviceError{ID: 65537, Err: ErrTimeout}}
+	assert.Equal("2 errors: device 65536: CoAP error 4.04 NotFound; device 65537: Timeout waiting for response", err.Error())
+	assert.True(errors.Is(err, ErrTimeout))
+	var deviceErr *DeviceError
+	assert.True(errors.As(err, &deviceErr))
+	assert.Equal(65536, deviceErr.ID)
+}
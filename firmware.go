@@ -0,0 +1,88 @@
+package tradfri
+
+import (
+	"context"
+)
+
+// FirmwareStatus is the firmware version and update state of a device or the
+// gateway. DeviceID is 0 for the gateway.
+type FirmwareStatus struct {
+	DeviceID        int
+	Name            string
+	Type            string
+	Model           string
+	FirmwareVersion string
+	State           OTAState
+}
+
+// FirmwareReport lists the firmware status of the gateway and its devices.
+type FirmwareReport struct {
+	Gateway FirmwareStatus
+	Devices []FirmwareStatus
+}
+
+// Updating returns the gateway and devices with updates pending or in
+// progress.
+func (r *FirmwareReport) Updating() []FirmwareStatus {
+	var updating []FirmwareStatus
+	for _, s := range append([]FirmwareStatus{r.Gateway}, r.Devices...) {
+		if s.State.Pending() || s.State.InProgress() {
+			updating = append(updating, s)
+		}
+	}
+	return updating
+}
+
+// GetFirmwareReport reads the firmware status of the gateway and every
+// device. Devices that cannot be read are omitted and reported as a
+// MultiError, as for ListDevicesConcurrent.
+func (c *Client) GetFirmwareReport() (*FirmwareReport, error) {
+	return c.GetFirmwareReportContext(context.Background())
+}
+
+func (c *Client) GetFirmwareReportContext(ctx context.Context) (*FirmwareReport, error) {
+	info, err := c.GetGatewayInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &FirmwareReport{
+		Gateway: FirmwareStatus{
+			Name:            "Gateway",
+			Type:            "Gateway",
+			FirmwareVersion: info.FirmwareVersion,
+			State:           info.OTAUpdateState,
+		},
+	}
+	devices, err := c.ListDevicesConcurrentContext(ctx, 4)
+	for _, d := range devices {
+		report.Devices = append(report.Devices, FirmwareStatus{
+			DeviceID:        d.DeviceID,
+			Name:            d.DeviceName,
+			Type:            d.ApplicationType.String(),
+			Model:           d.Device.ModelNumber,
+			FirmwareVersion: d.Device.FirmwareVersion,
+			State:           d.OTAUpdateState,
+		})
+	}
+	return report, err
+}
+
+// CheckForUpdates asks the gateway to check for new firmware for itself and
+// its devices. Devices update automatically once firmware is downloaded.
+func (c *Client) CheckForUpdates() error {
+	return c.CheckForUpdatesContext(context.Background())
+}
+
+func (c *Client) CheckForUpdatesContext(ctx context.Context) error {
+	return c.putRequest(ctx, uriGatewayInfo, OTACheckSet{1})
+}
+
+// InstallGatewayUpdate installs downloaded gateway firmware. The gateway
+// reboots, dropping the connection.
+func (c *Client) InstallGatewayUpdate() error {
+	return c.InstallGatewayUpdateContext(context.Background())
+}
+
+func (c *Client) InstallGatewayUpdateContext(ctx context.Context) error {
+	return c.postRequest(ctx, uriGatewayUpdate, nil, nil)
+}
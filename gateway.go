@@ -0,0 +1,90 @@
+package tradfri
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// SetNTPServer changes the time server used by the gateway.
+func (c *Client) SetNTPServer(server string) error {
+	return c.SetNTPServerContext(context.Background(), server)
+}
+
+func (c *Client) SetNTPServerContext(ctx context.Context, server string) error {
+	if server == "" {
+		return errors.New("NTP server must not be empty")
+	}
+	return c.putRequest(ctx, uriGatewayInfo, NTPServerSet{server})
+}
+
+// SetTimezone sets the gateway's UTC offset to the standard offset of loc,
+// and its daylight saving rule to that of loc in the current year. The
+// gateway keeps UTC time, so this only affects when smart tasks run. The rule
+// is stored as fixed dates, so SetTimezone must be called again each year.
+func (c *Client) SetTimezone(loc *time.Location) error {
+	return c.SetTimezoneContext(context.Background(), loc)
+}
+
+func (c *Client) SetTimezoneContext(ctx context.Context, loc *time.Location) error {
+	year := time.Now().Year()
+	payload := TimezoneSet{
+		UTCOffset:      standardOffset(loc, year),
+		DaylightSaving: DaylightSavingFor(loc, year),
+	}
+	return c.putRequest(ctx, uriGatewayInfo, payload)
+}
+
+// standardOffset returns the offset of loc from UTC in minutes outside
+// daylight saving in year.
+func standardOffset(loc *time.Location, year int) int {
+	_, winter := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, summer := time.Date(year, time.July, 1, 0, 0, 0, 0, loc).Zone()
+	if summer < winter {
+		winter = summer
+	}
+	return winter / 60
+}
+
+// DaylightSavingFor returns the daylight saving rule of loc in year, or a zero
+// rule if loc does not observe daylight saving.
+func DaylightSavingFor(loc *time.Location, year int) DaylightSaving {
+	var dst DaylightSaving
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := t.AddDate(1, 0, 0)
+	_, prev := t.In(loc).Zone()
+	for ; t.Before(end); t = t.Add(time.Hour) {
+		_, offset := t.In(loc).Zone()
+		if offset == prev {
+			continue
+		}
+		// some zones change on the half hour
+		at := transition(loc, t.Add(-time.Hour), t)
+		switch {
+		case offset > prev:
+			dst.StartMonth, dst.StartDay = int(at.Month()), at.Day()
+			dst.StartHour, dst.StartMinute = at.Hour(), at.Minute()
+			dst.Offset = (offset - prev) / 60
+		case offset < prev:
+			dst.EndMonth, dst.EndDay = int(at.Month()), at.Day()
+			dst.EndHour, dst.EndMinute = at.Hour(), at.Minute()
+		}
+		prev = offset
+	}
+	return dst
+}
+
+// transition returns the first minute after lo at which the offset of loc
+// differs from that at lo, given that it differs at hi.
+func transition(loc *time.Location, lo, hi time.Time) time.Time {
+	_, before := lo.In(loc).Zone()
+	for hi.Sub(lo) > time.Minute {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+		if _, offset := mid.In(loc).Zone(); offset == before {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
@@ -0,0 +1,111 @@
+package tradfri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ListMoodIds returns the ids of the moods of a group.
+func (c *Client) ListMoodIds(groupId int) ([]int, error) {
+	return c.ListMoodIdsContext(context.Background(), groupId)
+}
+
+func (c *Client) ListMoodIdsContext(ctx context.Context, groupId int) (moodIds []int, err error) {
+	uri := fmt.Sprintf("%s/%d", uriMoods, groupId)
+	err = c.getRequest(ctx, uri, &moodIds)
+	return
+}
+
+// ListMoods returns the moods of a group.
+func (c *Client) ListMoods(groupId int) ([]*Mood, error) {
+	return c.ListMoodsContext(context.Background(), groupId)
+}
+
+func (c *Client) ListMoodsContext(ctx context.Context, groupId int) (moods []*Mood, err error) {
+	moodIds, err := c.ListMoodIdsContext(ctx, groupId)
+	if err != nil {
+		return
+	}
+	for _, id := range moodIds {
+		var mood *Mood
+		mood, err = c.GetMoodContext(ctx, groupId, id)
+		if err != nil {
+			return
+		}
+		moods = append(moods, mood)
+	}
+	return
+}
+
+// GetMood returns a mood of a group.
+func (c *Client) GetMood(groupId, moodId int) (*Mood, error) {
+	return c.GetMoodContext(context.Background(), groupId, moodId)
+}
+
+func (c *Client) GetMoodContext(ctx context.Context, groupId, moodId int) (*Mood, error) {
+	uri := fmt.Sprintf("%s/%d/%d", uriMoods, groupId, moodId)
+	var mood Mood
+	err := c.getRequest(ctx, uri, &mood)
+	return &mood, err
+}
+
+// ActivateMood switches a group on and applies one of its moods.
+func (c *Client) ActivateMood(groupId, moodId int) error {
+	return c.ActivateMoodContext(context.Background(), groupId, moodId)
+}
+
+func (c *Client) ActivateMoodContext(ctx context.Context, groupId, moodId int) error {
+	uri := fmt.Sprintf("%s/%d", uriGroups, groupId)
+	return c.putRequest(ctx, uri, MoodActivate{Power: 1, ActiveMood: moodId})
+}
+
+// CreateMood stores the current state of a group's lights as a new mood,
+// returning its id.
+func (c *Client) CreateMood(groupId int, name string) (int, error) {
+	return c.CreateMoodContext(context.Background(), groupId, name)
+}
+
+func (c *Client) CreateMoodContext(ctx context.Context, groupId int, name string) (int, error) {
+	if name == "" {
+		return 0, errors.New("Name must not be empty")
+	}
+	group, err := c.GetGroupDescriptionContext(ctx, groupId)
+	if err != nil {
+		return 0, err
+	}
+	payload := MoodCreate{Name: name, LightSettings: []LightSetting{}}
+	for _, id := range group.AccessoryLink.LinkedItems.DeviceIDs {
+		device, err := c.GetDeviceDescriptionContext(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if !device.IsLight() || len(device.LightControl) == 0 {
+			continue
+		}
+		// everything but the transition time, so colour is kept however
+		// it was set
+		lc := device.LightControl[0]
+		lc.Duration = nil
+		payload.LightSettings = append(payload.LightSettings, LightSetting{
+			DeviceID:     id,
+			LightControl: lc,
+		})
+	}
+	var created MoodCreated
+	uri := fmt.Sprintf("%s/%d", uriMoods, groupId)
+	if err := c.postRequest(ctx, uri, payload, &created); err != nil {
+		return 0, err
+	}
+	return created.MoodID, nil
+}
+
+// DeleteMood deletes a mood of a group.
+func (c *Client) DeleteMood(groupId, moodId int) error {
+	return c.DeleteMoodContext(context.Background(), groupId, moodId)
+}
+
+func (c *Client) DeleteMoodContext(ctx context.Context, groupId, moodId int) error {
+	uri := fmt.Sprintf("%s/%d/%d", uriMoods, groupId, moodId)
+	return c.deleteRequest(ctx, uri)
+}
@@ -0,0 +1,78 @@
+package tradfri
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/barnybug/go-tradfri/log"
+	"github.com/dustin/go-coap"
+)
+
+// ListNotifications returns the notifications held by the gateway.
+func (c *Client) ListNotifications() ([]*Notification, error) {
+	return c.ListNotificationsContext(context.Background())
+}
+
+func (c *Client) ListNotificationsContext(ctx context.Context) (notifications []*Notification, err error) {
+	err = c.getRequest(ctx, uriNotifications, &notifications)
+	return
+}
+
+// ObserveNotifications sends each notification raised from now on to Events
+// as a *Notification.
+func (c *Client) ObserveNotifications() error {
+	return c.ObserveNotificationsContext(context.Background())
+}
+
+func (c *Client) ObserveNotificationsContext(ctx context.Context) error {
+	return c.ObserveContext(ctx, uriNotifications)
+}
+
+type notificationKey struct {
+	id, createdAt int
+	event         NotificationEvent
+}
+
+// notificationObserver sends notifications not seen before, as the gateway
+// notifies the whole list each time. The first list received sets the
+// baseline; the seen set is kept on the Client so notifications raised while
+// the session was down are sent once the observation is re-registered.
+func (c *Client) notificationObserver(in <-chan coap.Message) {
+	for msg := range in {
+		if err := responseError(msg); err != nil {
+			log.Printf("Error observing %s: %s", uriNotifications, err)
+			continue
+		}
+		var notifications []*Notification
+		if err := json.Unmarshal(msg.Payload, &notifications); err != nil {
+			log.Printf("Error decoding %s: %s", uriNotifications, err)
+			continue
+		}
+		for _, n := range c.unseenNotifications(notifications) {
+			c.emit(n)
+		}
+	}
+}
+
+// unseenNotifications records notifications as seen, returning those not seen
+// before. Nothing is returned the first time.
+func (c *Client) unseenNotifications(notifications []*Notification) []*Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	first := c.notificationsSeen == nil
+	if first {
+		c.notificationsSeen = map[notificationKey]bool{}
+	}
+	var unseen []*Notification
+	for _, n := range notifications {
+		key := notificationKey{n.NotificationID, n.CreatedAt, n.Event}
+		if c.notificationsSeen[key] {
+			continue
+		}
+		c.notificationsSeen[key] = true
+		if !first {
+			unseen = append(unseen, n)
+		}
+	}
+	return unseen
+}
@@ -0,0 +1,20 @@
+package tradfri
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetPlug switches a control outlet on or off.
+func (c *Client) SetPlug(deviceId int, on bool) error {
+	return c.SetPlugContext(context.Background(), deviceId, on)
+}
+
+func (c *Client) SetPlugContext(ctx context.Context, deviceId int, on bool) error {
+	power := boolToInt(on)
+	payload := PlugSet{
+		[]PlugControl{{Power: &power}},
+	}
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.putRequest(ctx, uri, payload)
+}
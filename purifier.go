@@ -0,0 +1,70 @@
+package tradfri
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetAirPurifier applies change to an air purifier.
+func (c *Client) SetAirPurifier(deviceId int, change AirPurifierControl) error {
+	return c.SetAirPurifierContext(context.Background(), deviceId, change)
+}
+
+func (c *Client) SetAirPurifierContext(ctx context.Context, deviceId int, change AirPurifierControl) error {
+	payload := AirPurifierSet{
+		[]AirPurifierControl{change},
+	}
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.putRequest(ctx, uri, payload)
+}
+
+// SetAirPurifierMode sets the fan mode to FanModeOff or FanModeAuto.
+func (c *Client) SetAirPurifierMode(deviceId int, mode int) error {
+	return c.SetAirPurifierModeContext(context.Background(), deviceId, mode)
+}
+
+func (c *Client) SetAirPurifierModeContext(ctx context.Context, deviceId int, mode int) error {
+	if mode != FanModeOff && mode != FanModeAuto {
+		return fmt.Errorf("Fan mode must be %d (off) or %d (auto), got %d", FanModeOff, FanModeAuto, mode)
+	}
+	return c.SetAirPurifierContext(ctx, deviceId, AirPurifierControl{FanMode: &mode})
+}
+
+// SetAirPurifierSpeed sets a manual fan speed from FanSpeedMin to FanSpeedMax.
+func (c *Client) SetAirPurifierSpeed(deviceId int, speed int) error {
+	return c.SetAirPurifierSpeedContext(context.Background(), deviceId, speed)
+}
+
+func (c *Client) SetAirPurifierSpeedContext(ctx context.Context, deviceId int, speed int) error {
+	if speed < FanSpeedMin || speed > FanSpeedMax {
+		return fmt.Errorf("Fan speed must be %d-%d, got %d", FanSpeedMin, FanSpeedMax, speed)
+	}
+	return c.SetAirPurifierContext(ctx, deviceId, AirPurifierControl{FanSpeed: &speed})
+}
+
+// SetAirPurifierChildLock locks or unlocks the buttons on an air purifier.
+func (c *Client) SetAirPurifierChildLock(deviceId int, locked bool) error {
+	return c.SetAirPurifierChildLockContext(context.Background(), deviceId, locked)
+}
+
+func (c *Client) SetAirPurifierChildLockContext(ctx context.Context, deviceId int, locked bool) error {
+	lock := boolToInt(locked)
+	return c.SetAirPurifierContext(ctx, deviceId, AirPurifierControl{ChildLock: &lock})
+}
+
+// SetAirPurifierLEDs switches the status LEDs of an air purifier on or off.
+func (c *Client) SetAirPurifierLEDs(deviceId int, on bool) error {
+	return c.SetAirPurifierLEDsContext(context.Background(), deviceId, on)
+}
+
+func (c *Client) SetAirPurifierLEDsContext(ctx context.Context, deviceId int, on bool) error {
+	disable := boolToInt(!on)
+	return c.SetAirPurifierContext(ctx, deviceId, AirPurifierControl{LEDDisable: &disable})
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
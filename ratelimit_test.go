@@ -0,0 +1,41 @@
+package tradfri
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.NoError(t, l.wait(ctx))
+	}
+	// 2 immediately from the burst, then 4 at 10ms intervals
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 35*time.Millisecond, elapsed)
+	assert.True(t, elapsed < 200*time.Millisecond, elapsed)
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	var l *rateLimiter
+	assert.NoError(t, l.wait(context.Background()))
+	l = newRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, l.wait(context.Background()))
+	}
+}
+
+func TestRateLimiterContext(t *testing.T) {
+	l := newRateLimiter(1, 1)
+	assert.NoError(t, l.wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.wait(ctx))
+	// the cancelled reservation is returned to the bucket
+	assert.InDelta(t, 0, l.tokens, 0.1)
+}
@@ -0,0 +1,320 @@
+package tradfri
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/barnybug/go-tradfri/log"
+	"github.com/dustin/go-coap"
+)
+
+// ConnState describes the state of the connection to the gateway.
+type ConnState int
+
+const (
+	StateDisconnected ConnState = iota
+	StateConnected
+	StateReconnecting
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("ConnState(%d)", int(s))
+}
+
+// State returns the current connection state.
+func (c *Client) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Close disconnects from the gateway and stops reconnecting. Events is closed
+// once every observation has stopped.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	close(c.closed)
+	var err error
+	if c.client != nil {
+		err = c.client.Close()
+		c.client = nil
+	}
+	c.setState(StateDisconnected)
+	events := c.events
+	go func() {
+		c.observers.Wait()
+		close(events)
+	}()
+	return err
+}
+
+// init creates the channels of a Client not built with NewClient. It must be
+// called with c.mu held.
+func (c *Client) init() {
+	if c.events == nil {
+		c.events = make(chan interface{}, 16)
+	}
+	if c.closed == nil {
+		c.closed = make(chan struct{})
+	}
+}
+
+// setState must be called with c.mu held.
+func (c *Client) setState(state ConnState) {
+	if state == c.state {
+		return
+	}
+	log.Printf("Connection %s", state)
+	c.state = state
+	if c.OnStateChange != nil {
+		c.OnStateChange(state)
+	}
+}
+
+func (c *Client) dial() (Transport, error) {
+	if c.Dial != nil {
+		return c.Dial()
+	}
+	address := c.gatewayAddress()
+	log.Printf("Connecting to gateway: %s", address)
+	dc, err := NewDtlsClient(address, c.Ident, c.PSK)
+	if err != nil {
+		return nil, err
+	}
+	dc.Params = c.Transmission
+	return dc, nil
+}
+
+// conn returns the current connection, reconnecting first if a previous
+// reconnection gave up.
+func (c *Client) conn(ctx context.Context) (Transport, error) {
+	c.mu.Lock()
+	t := c.client
+	c.mu.Unlock()
+	if t != nil {
+		return t, nil
+	}
+	return c.reconnect(ctx, nil)
+}
+
+// reconnect replaces the connection old with a new one, re-handshaking with
+// the saved Ident/PSK and re-registering observations. If another goroutine
+// has already replaced old, its connection is returned. Dialing happens in
+// the background without holding c.mu, so callers stop waiting when ctx is
+// done or the Client is closed.
+func (c *Client) reconnect(ctx context.Context, old Transport) (Transport, error) {
+	c.mu.Lock()
+	c.init()
+	closed := c.closed
+	select {
+	case <-closed:
+		c.mu.Unlock()
+		return nil, ErrClosed
+	default:
+	}
+	if c.client != nil && c.client != old {
+		t := c.client
+		c.mu.Unlock()
+		return t, nil
+	}
+	if c.reconnecting == nil {
+		if c.client != nil {
+			c.client.Close()
+			c.client = nil
+		}
+		c.reconnecting = make(chan struct{})
+		c.setState(StateReconnecting)
+		go c.redial(c.reconnecting, closed)
+	}
+	done := c.reconnecting
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-closed:
+		return nil, ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	select {
+	case <-closed:
+		return nil, ErrClosed
+	default:
+		return nil, c.reconnectErr
+	}
+}
+
+// redial reconnects in the background, closing done once it has succeeded or
+// given up. Observations are re-registered afterwards, without holding c.mu.
+func (c *Client) redial(done, closed chan struct{}) {
+	t, err := c.dialWithBackoff(closed)
+
+	c.mu.Lock()
+	c.reconnecting = nil
+	select {
+	case <-closed:
+		c.mu.Unlock()
+		close(done)
+		if t != nil {
+			t.Close()
+		}
+		return
+	default:
+	}
+	c.reconnectErr = err
+	if err != nil {
+		c.setState(StateDisconnected)
+		c.mu.Unlock()
+		close(done)
+		return
+	}
+	c.client = t
+	c.setState(StateConnected)
+	uris := append([]string(nil), c.observed...)
+	c.mu.Unlock()
+	close(done)
+
+	c.reobserve(t, uris, closed)
+}
+
+// reobserve re-registers observations on t, paced by the rate limiter, until
+// closed is closed.
+func (c *Client) reobserve(t Transport, uris []string, closed chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for _, uri := range uris {
+		if err := c.limiter.wait(ctx); err != nil {
+			return
+		}
+		in, err := t.ObserveContext(ctx, uri)
+		if err != nil {
+			log.Errorf("Unable to re-observe %s: %s", uri, err)
+			continue
+		}
+		if !c.startObserver(uri, in) {
+			return
+		}
+	}
+}
+
+// dialWithBackoff makes up to ReconnectAttempts connection attempts, returning
+// ErrClosed if closed is closed meanwhile.
+func (c *Client) dialWithBackoff(closed chan struct{}) (Transport, error) {
+	backoff := c.ReconnectBackoff
+	for attempt := 1; ; attempt++ {
+		t, err := c.dial()
+		select {
+		case <-closed:
+			if t != nil {
+				t.Close()
+			}
+			return nil, ErrClosed
+		default:
+		}
+		if err == nil || attempt >= c.ReconnectAttempts {
+			return t, err
+		}
+		log.Printf("Reconnect attempt %d failed, retrying in %s: %s", attempt, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-closed:
+			return nil, ErrClosed
+		}
+		backoff *= 2
+		if backoff > c.MaxReconnectBackoff {
+			backoff = c.MaxReconnectBackoff
+		}
+	}
+}
+
+// call sends a request to the gateway. If the gateway has stopped responding
+// the connection is re-established, and idempotent requests are retried while
+// ctx allows. A context deadline shorter than the acknowledgement timeout is
+// not taken as a sign of a lost session.
+func (c *Client) call(ctx context.Context, code coap.COAPCode, uri string, payload []byte) (coap.Message, error) {
+	t, err := c.conn(ctx)
+	if err != nil {
+		return coap.Message{}, err
+	}
+	if err := c.limiter.wait(ctx); err != nil {
+		return coap.Message{}, err
+	}
+	start := time.Now()
+	resp, err := t.Do(ctx, code, uri, payload)
+	lost := err == ErrTimeout ||
+		err == context.DeadlineExceeded && time.Since(start) >= c.Transmission.withDefaults().AckTimeout
+	if !lost {
+		return resp, err
+	}
+
+	log.Printf("No response from gateway, reconnecting")
+	t, rerr := c.reconnect(ctx, t)
+	if rerr != nil {
+		return coap.Message{}, rerr
+	}
+	if code == coap.POST || ctx.Err() != nil {
+		return coap.Message{}, err
+	}
+	if err := c.limiter.wait(ctx); err != nil {
+		return coap.Message{}, err
+	}
+	return t.Do(ctx, code, uri, payload)
+}
+
+// keepAlive pings the gateway while resources are observed, reconnecting
+// when the session has been lost or an earlier reconnection gave up.
+func (c *Client) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		t := c.client
+		observing := len(c.observed) > 0
+		c.mu.Unlock()
+		if !observing {
+			continue
+		}
+		if t == nil {
+			// an earlier reconnection gave up; nothing else will retry
+			// for a consumer that only observes
+			c.reconnect(context.Background(), nil)
+			continue
+		}
+		if err := t.Ping(); err != nil {
+			log.Printf("Keepalive failed: %s", err)
+			c.reconnect(context.Background(), t)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tradfri
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ListSmartTaskIds returns the ids of the gateway's smart tasks.
+func (c *Client) ListSmartTaskIds() ([]int, error) {
+	return c.ListSmartTaskIdsContext(context.Background())
+}
+
+func (c *Client) ListSmartTaskIdsContext(ctx context.Context) (taskIds []int, err error) {
+	err = c.getRequest(ctx, uriSmartTasks, &taskIds)
+	return
+}
+
+// ListSmartTasks returns the gateway's smart tasks.
+func (c *Client) ListSmartTasks() ([]*SmartTask, error) {
+	return c.ListSmartTasksContext(context.Background())
+}
+
+func (c *Client) ListSmartTasksContext(ctx context.Context) (tasks []*SmartTask, err error) {
+	taskIds, err := c.ListSmartTaskIdsContext(ctx)
+	if err != nil {
+		return
+	}
+	for _, id := range taskIds {
+		var task *SmartTask
+		task, err = c.GetSmartTaskContext(ctx, id)
+		if err != nil {
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	return
+}
+
+// GetSmartTask returns a smart task.
+func (c *Client) GetSmartTask(taskId int) (*SmartTask, error) {
+	return c.GetSmartTaskContext(context.Background(), taskId)
+}
+
+func (c *Client) GetSmartTaskContext(ctx context.Context, taskId int) (*SmartTask, error) {
+	uri := fmt.Sprintf("%s/%d", uriSmartTasks, taskId)
+	var task SmartTask
+	err := c.getRequest(ctx, uri, &task)
+	return &task, err
+}
+
+// CreateSmartTask stores a new smart task, returning its id. TaskID and
+// CreatedAt are ignored; set Enabled to 1 for the task to run.
+func (c *Client) CreateSmartTask(task SmartTask) (int, error) {
+	return c.CreateSmartTaskContext(context.Background(), task)
+}
+
+func (c *Client) CreateSmartTaskContext(ctx context.Context, task SmartTask) (int, error) {
+	if err := task.validate(); err != nil {
+		return 0, err
+	}
+	task.TaskID = 0
+	task.CreatedAt = 0
+	var created SmartTaskCreated
+	if err := c.postRequest(ctx, uriSmartTasks, task, &created); err != nil {
+		return 0, err
+	}
+	return created.TaskID, nil
+}
+
+// UpdateSmartTask replaces the smart task with id task.TaskID.
+func (c *Client) UpdateSmartTask(task SmartTask) error {
+	return c.UpdateSmartTaskContext(context.Background(), task)
+}
+
+func (c *Client) UpdateSmartTaskContext(ctx context.Context, task SmartTask) error {
+	if err := task.validate(); err != nil {
+		return err
+	}
+	uri := fmt.Sprintf("%s/%d", uriSmartTasks, task.TaskID)
+	task.CreatedAt = 0
+	return c.putRequest(ctx, uri, task)
+}
+
+// DeleteSmartTask deletes a smart task.
+func (c *Client) DeleteSmartTask(taskId int) error {
+	return c.DeleteSmartTaskContext(context.Background(), taskId)
+}
+
+func (c *Client) DeleteSmartTaskContext(ctx context.Context, taskId int) error {
+	uri := fmt.Sprintf("%s/%d", uriSmartTasks, taskId)
+	return c.deleteRequest(ctx, uri)
+}
+
+// EnableSmartTask enables or disables a smart task.
+func (c *Client) EnableSmartTask(taskId int, enabled bool) error {
+	return c.EnableSmartTaskContext(context.Background(), taskId, enabled)
+}
+
+func (c *Client) EnableSmartTaskContext(ctx context.Context, taskId int, enabled bool) error {
+	uri := fmt.Sprintf("%s/%d", uriSmartTasks, taskId)
+	return c.putRequest(ctx, uri, SmartTaskEnable{boolToInt(enabled)})
+}
+
+func (t *SmartTask) validate() error {
+	if _, ok := SmartTaskTypes[t.Type]; !ok {
+		return fmt.Errorf("Unknown smart task type %d", t.Type)
+	}
+	if t.RepeatDays < Monday || t.RepeatDays > EveryDay {
+		return fmt.Errorf("Invalid repeat days %d", t.RepeatDays)
+	}
+	if len(t.Intervals) == 0 {
+		return errors.New("Smart task needs a time interval")
+	}
+	for _, i := range t.Intervals {
+		if i.StartHour < 0 || i.StartHour > 23 || i.StartMinute < 0 || i.StartMinute > 59 {
+			return fmt.Errorf("Invalid time interval %s", i)
+		}
+		if (i.EndHour == nil) != (i.EndMinute == nil) {
+			return fmt.Errorf("Time interval %s needs both end hour and minute", i)
+		}
+		if i.EndHour != nil && (*i.EndHour < 0 || *i.EndHour > 23 || *i.EndMinute < 0 || *i.EndMinute > 59) {
+			return fmt.Errorf("Invalid time interval %s", i)
+		}
+	}
+	return nil
+}
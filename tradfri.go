@@ -1,14 +1,20 @@
 package tradfri
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"net"
 	"os"
 	"os/user"
 	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/barnybug/go-tradfri/log"
@@ -21,7 +27,44 @@ type Client struct {
 	Ident   string
 	PSK     string
 
-	client *DtlsClient
+	// Transmission controls retransmission of requests to the gateway.
+	Transmission TransmissionParams
+	// Requests are limited to RateLimit per second, with bursts of up to
+	// RateBurst, to avoid the gateway's flood protection. A RateLimit of zero
+	// disables limiting. Changes take effect on Connect.
+	RateLimit float64
+	RateBurst int
+	// Reconnection is attempted up to ReconnectAttempts times, with the delay
+	// between attempts doubling from ReconnectBackoff to MaxReconnectBackoff.
+	ReconnectAttempts   int
+	ReconnectBackoff    time.Duration
+	MaxReconnectBackoff time.Duration
+	// KeepAlive is the interval between pings of the gateway while resources
+	// are observed, so a lost session is noticed without other traffic. Zero
+	// disables pinging.
+	KeepAlive time.Duration
+	// Dial, if set, is used to connect instead of DTLS, for example to drive
+	// the Client over UDPClient or an in-memory Transport. PSK generation is
+	// skipped.
+	Dial func() (Transport, error)
+	// OnStateChange, if set, is called whenever the connection state changes.
+	// It must not call back into the Client.
+	OnStateChange func(ConnState)
+
+	mu           sync.Mutex // guards the fields below
+	client       Transport
+	limiter      *rateLimiter
+	state        ConnState
+	observed     []string
+	reconnecting chan struct{} // closed when a reconnection finishes
+	reconnectErr error
+	// notificationsSeen is nil until the first notification list arrives
+	notificationsSeen map[notificationKey]bool
+	events            chan interface{}
+	closed            chan struct{}
+	// observers counts the running observer goroutines, so events can be
+	// closed once they have stopped
+	observers sync.WaitGroup
 }
 
 func SetDebug(debug bool) {
@@ -30,23 +73,48 @@ func SetDebug(debug bool) {
 
 func NewClient(gateway string) *Client {
 	return &Client{
-		Gateway: gateway,
+		Gateway:             gateway,
+		Transmission:        DefaultTransmissionParams,
+		RateLimit:           10,
+		RateBurst:           1,
+		ReconnectAttempts:   10,
+		ReconnectBackoff:    time.Second,
+		MaxReconnectBackoff: time.Minute,
+		KeepAlive:           time.Minute,
 	}
 }
 
 func (c *Client) Connect() error {
-	if c.PSK == "" {
+	if c.PSK == "" && c.Dial == nil {
 		err := c.generatePSK()
 		if err != nil {
 			return err
 		}
 	}
 
-	address := fmt.Sprintf("%s:%d", c.Gateway, tradfriPort)
-	log.Printf("Connecting to gateway: %s", address)
-	var err error
-	c.client, err = NewDtlsClient(address, c.Ident, c.PSK)
-	return err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	c.limiter = newRateLimiter(c.RateLimit, c.RateBurst)
+	t, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.client = t
+	c.setState(StateConnected)
+	if c.KeepAlive > 0 {
+		go c.keepAlive(c.KeepAlive)
+	}
+	return nil
+}
+
+// gatewayAddress returns the host:port of the gateway. Gateway may include a
+// port, otherwise the standard CoAPS port is used.
+func (c *Client) gatewayAddress() string {
+	if _, _, err := net.SplitHostPort(c.Gateway); err == nil {
+		return c.Gateway
+	}
+	return net.JoinHostPort(c.Gateway, strconv.Itoa(tradfriPort))
 }
 
 func pskPath() string {
@@ -98,12 +166,12 @@ func (c *Client) generatePSK() error {
 		log.Printf("Using ident: %s", c.Ident)
 	}
 	log.Println("Requesting PSK...")
-	address := fmt.Sprintf("%s:%d", c.Gateway, tradfriPort)
-
-	client, err := NewDtlsClient(address, "Client_identity", c.Key)
+	client, err := NewDtlsClient(c.gatewayAddress(), preauthIdentity, c.Key)
 	if err != nil {
 		return err
 	}
+	defer client.Close()
+	client.Params = c.Transmission
 	payload := PSKRequest{Ident: c.Ident}
 	data, _ := json.Marshal(payload)
 	req := client.BuildPOSTMessage(uriIdent, string(data))
@@ -121,23 +189,49 @@ func (c *Client) generatePSK() error {
 		log.Printf("PSK: %s\n", c.PSK)
 		return nil
 	}
+	if err := responseError(resp); err != nil {
+		return fmt.Errorf("Unable to get PSK: %w", err)
+	}
 	return errors.New("Unable to get PSK")
 }
 
-func (c *Client) putRequest(uri string, payload interface{}) error {
+func (c *Client) putRequest(ctx context.Context, uri string, payload interface{}) error {
 	data, _ := json.Marshal(payload)
-	req := c.client.BuildPUTMessage(uri, string(data))
-	_, err := c.client.Call(req)
+	resp, err := c.call(ctx, coap.PUT, uri, data)
+	if err == nil {
+		err = responseError(resp)
+	}
+	if err != nil {
+		log.Printf("<- error: %+v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) postRequest(ctx context.Context, uri string, payload interface{}, out interface{}) error {
+	var data []byte
+	if payload != nil {
+		data, _ = json.Marshal(payload)
+	}
+	resp, err := c.call(ctx, coap.POST, uri, data)
+	if err == nil {
+		err = responseError(resp)
+	}
 	if err != nil {
 		log.Printf("<- error: %+v", err)
 		return err
 	}
+	if out != nil {
+		return json.Unmarshal(resp.Payload, out)
+	}
 	return nil
 }
 
-func (c *Client) postRequest(uri string) error {
-	req := c.client.BuildPOSTMessage(uri, "")
-	_, err := c.client.Call(req)
+func (c *Client) deleteRequest(ctx context.Context, uri string) error {
+	resp, err := c.call(ctx, coap.DELETE, uri, nil)
+	if err == nil {
+		err = responseError(resp)
+	}
 	if err != nil {
 		log.Printf("<- error: %+v", err)
 		return err
@@ -145,9 +239,11 @@ func (c *Client) postRequest(uri string) error {
 	return nil
 }
 
-func (c *Client) getRequest(uri string, out interface{}) error {
-	req := c.client.BuildGETMessage(uri)
-	resp, err := c.client.Call(req)
+func (c *Client) getRequest(ctx context.Context, uri string, out interface{}) error {
+	resp, err := c.call(ctx, coap.GET, uri, nil)
+	if err == nil {
+		err = responseError(resp)
+	}
 	if err != nil {
 		log.Printf("<- error: %+v", err)
 		return err
@@ -157,27 +253,47 @@ func (c *Client) getRequest(uri string, out interface{}) error {
 }
 
 func (c *Client) GetGatewayInfo() (*GatewayInfo, error) {
+	return c.GetGatewayInfoContext(context.Background())
+}
+
+func (c *Client) GetGatewayInfoContext(ctx context.Context) (*GatewayInfo, error) {
 	var gatewayInfo GatewayInfo
-	err := c.getRequest(uriGatewayInfo, &gatewayInfo)
+	err := c.getRequest(ctx, uriGatewayInfo, &gatewayInfo)
 	return &gatewayInfo, err
 }
 
 func (c *Client) Reboot() error {
-	return c.postRequest(uriGatewayReboot)
+	return c.RebootContext(context.Background())
+}
+
+func (c *Client) RebootContext(ctx context.Context) error {
+	return c.postRequest(ctx, uriGatewayReboot, nil, nil)
 }
 
 func (c *Client) FactoryReset() error {
-	return c.postRequest(uriGatewayFactoryReset)
+	return c.FactoryResetContext(context.Background())
+}
+
+func (c *Client) FactoryResetContext(ctx context.Context) error {
+	return c.postRequest(ctx, uriGatewayFactoryReset, nil, nil)
+}
+
+func (c *Client) ListDeviceIds() ([]int, error) {
+	return c.ListDeviceIdsContext(context.Background())
 }
 
-func (c *Client) ListDeviceIds() (deviceIds []int, err error) {
+func (c *Client) ListDeviceIdsContext(ctx context.Context) (deviceIds []int, err error) {
 	log.Println("Looking for devices... ")
-	err = c.getRequest(uriDevices, &deviceIds)
+	err = c.getRequest(ctx, uriDevices, &deviceIds)
 	return deviceIds, err
 }
 
-func (c *Client) ListDevices() (devices []*DeviceDescription, err error) {
-	deviceIds, err := c.ListDeviceIds()
+func (c *Client) ListDevices() ([]*DeviceDescription, error) {
+	return c.ListDevicesContext(context.Background())
+}
+
+func (c *Client) ListDevicesContext(ctx context.Context) (devices []*DeviceDescription, err error) {
+	deviceIds, err := c.ListDeviceIdsContext(ctx)
 	if err != nil {
 		return
 	}
@@ -185,39 +301,169 @@ func (c *Client) ListDevices() (devices []*DeviceDescription, err error) {
 	log.Println("Enumerating...")
 	for _, device := range deviceIds {
 		var desc *DeviceDescription
-		desc, err = c.GetDeviceDescription(device)
+		desc, err = c.GetDeviceDescriptionContext(ctx, device)
 		if err != nil {
 			return
 		}
 		log.Printf("Found device: %s\n", desc)
 		devices = append(devices, desc)
-
-		// sleep for a while to avoid flood protection
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	return
 }
 
+// ListDevicesConcurrent fetches device descriptions with up to n requests in
+// flight, subject to the client's rate limit. Devices that fail are omitted
+// from the result and reported as a MultiError of *DeviceError.
+func (c *Client) ListDevicesConcurrent(n int) ([]*DeviceDescription, error) {
+	return c.ListDevicesConcurrentContext(context.Background(), n)
+}
+
+func (c *Client) ListDevicesConcurrentContext(ctx context.Context, n int) ([]*DeviceDescription, error) {
+	deviceIds, err := c.ListDeviceIdsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	log.Println("Enumerating...")
+	descs := make([]*DeviceDescription, len(deviceIds))
+	errs := make([]error, len(deviceIds))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n && w < len(deviceIds); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				descs[i], errs[i] = c.GetDeviceDescriptionContext(ctx, deviceIds[i])
+			}
+		}()
+	}
+	for i := range deviceIds {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var devices []*DeviceDescription
+	var multi MultiError
+	for i, desc := range descs {
+		if errs[i] != nil {
+			multi = append(multi, &DeviceError{ID: deviceIds[i], Err: errs[i]})
+			continue
+		}
+		log.Printf("Found device: %s\n", desc)
+		devices = append(devices, desc)
+	}
+	if multi != nil {
+		return devices, multi
+	}
+	return devices, nil
+}
+
 func (c *Client) GetDeviceDescription(id int) (*DeviceDescription, error) {
+	return c.GetDeviceDescriptionContext(context.Background(), id)
+}
+
+func (c *Client) GetDeviceDescriptionContext(ctx context.Context, id int) (*DeviceDescription, error) {
 	uri := fmt.Sprintf("%s/%d", uriDevices, id)
 	var desc DeviceDescription
-	err := c.getRequest(uri, &desc)
+	err := c.getRequest(ctx, uri, &desc)
 	return &desc, err
 }
 
 func (c *Client) SetDevice(deviceId int, change LightControl) error {
+	return c.SetDeviceContext(context.Background(), deviceId, change)
+}
+
+func (c *Client) SetDeviceContext(ctx context.Context, deviceId int, change LightControl) error {
 	payload := DeviceSet{
 		[]LightControl{change},
 	}
 	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
-	return c.putRequest(uri, payload)
+	return c.putRequest(ctx, uri, payload)
+}
+
+// GetDeviceChannel returns light channel index of a multi-socket fixture.
+func (c *Client) GetDeviceChannel(deviceId, index int) (*LightControl, error) {
+	return c.GetDeviceChannelContext(context.Background(), deviceId, index)
+}
+
+func (c *Client) GetDeviceChannelContext(ctx context.Context, deviceId, index int) (*LightControl, error) {
+	desc, err := c.GetDeviceDescriptionContext(ctx, deviceId)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(desc.LightControl) {
+		return nil, fmt.Errorf("Device %d has no light channel %d", deviceId, index)
+	}
+	return &desc.LightControl[index], nil
+}
+
+// SetDeviceChannel applies change to light channel index of a multi-socket
+// fixture. The gateway expects the full array, so the other channels are sent
+// with their current values.
+func (c *Client) SetDeviceChannel(deviceId, index int, change LightControl) error {
+	return c.SetDeviceChannelContext(context.Background(), deviceId, index, change)
+}
+
+func (c *Client) SetDeviceChannelContext(ctx context.Context, deviceId, index int, change LightControl) error {
+	desc, err := c.GetDeviceDescriptionContext(ctx, deviceId)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(desc.LightControl) {
+		return fmt.Errorf("Device %d has no light channel %d", deviceId, index)
+	}
+	payload := DeviceSet{desc.LightControl}
+	payload.LightControl[index] = change
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.putRequest(ctx, uri, payload)
+}
+
+// RenameDevice changes the name of a device.
+func (c *Client) RenameDevice(deviceId int, name string) error {
+	return c.RenameDeviceContext(context.Background(), deviceId, name)
+}
+
+func (c *Client) RenameDeviceContext(ctx context.Context, deviceId int, name string) error {
+	if name == "" {
+		return errors.New("Name must not be empty")
+	}
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.putRequest(ctx, uri, NameSet{name})
+}
+
+// RemoveDevice unpairs a device from the gateway. It must be paired again to
+// be used.
+func (c *Client) RemoveDevice(deviceId int) error {
+	return c.RemoveDeviceContext(context.Background(), deviceId)
+}
+
+func (c *Client) RemoveDeviceContext(ctx context.Context, deviceId int) error {
+	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
+	return c.deleteRequest(ctx, uri)
+}
+
+func (c *Client) ListGroupIds() ([]int, error) {
+	return c.ListGroupIdsContext(context.Background())
 }
 
-func (c *Client) ListGroups() (groups []*GroupDescription, err error) {
+func (c *Client) ListGroupIdsContext(ctx context.Context) (groupIds []int, err error) {
 	log.Println("Requesting groups... ")
-	var groupIds []int
-	err = c.getRequest(uriGroups, &groupIds)
+	err = c.getRequest(ctx, uriGroups, &groupIds)
+	return groupIds, err
+}
+
+func (c *Client) ListGroups() ([]*GroupDescription, error) {
+	return c.ListGroupsContext(context.Background())
+}
+
+func (c *Client) ListGroupsContext(ctx context.Context) (groups []*GroupDescription, err error) {
+	groupIds, err := c.ListGroupIdsContext(ctx)
 	if err != nil {
 		return
 	}
@@ -225,56 +471,237 @@ func (c *Client) ListGroups() (groups []*GroupDescription, err error) {
 	log.Println("Enumerating...")
 	for _, group := range groupIds {
 		var desc *GroupDescription
-		desc, err = c.GetGroupDescription(group)
+		desc, err = c.GetGroupDescriptionContext(ctx, group)
 		if err != nil {
 			return
 		}
-		log.Println("Found group: %+v\n", desc)
+		log.Printf("Found group: %+v\n", desc)
 		groups = append(groups, desc)
-
-		// sleep for a while to avoid flood protection
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	return
 }
 
 func (c *Client) GetGroupDescription(id int) (*GroupDescription, error) {
+	return c.GetGroupDescriptionContext(context.Background(), id)
+}
+
+func (c *Client) GetGroupDescriptionContext(ctx context.Context, id int) (*GroupDescription, error) {
 	uri := fmt.Sprintf("%s/%d", uriGroups, id)
 	var desc GroupDescription
-	err := c.getRequest(uri, &desc)
+	err := c.getRequest(ctx, uri, &desc)
 	return &desc, err
 }
 
 func (c *Client) SetGroup(groupId int, change LightControl) error {
+	return c.SetGroupContext(context.Background(), groupId, change)
+}
+
+func (c *Client) SetGroupContext(ctx context.Context, groupId int, change LightControl) error {
 	payload := change
 	uri := fmt.Sprintf("%s/%d", uriGroups, groupId)
-	return c.putRequest(uri, payload)
-}
-
-// func (c *Client) observer(in chan canopus.ObserveMessage, out chan *DeviceDescription) {
-// 	for msg := range in {
-// 		value := msg.GetValue()
-// 		if value, ok := value.(canopus.MessagePayload); ok {
-// 			dd := &DeviceDescription{}
-// 			err := json.Unmarshal(value.GetBytes(), dd)
-// 			if err == nil {
-// 				out <- dd
-// 			}
-// 		}
-// 	}
-// }
-
-// func (c *Client) Events() <-chan *DeviceDescription {
-// 	out := make(chan *DeviceDescription, 16)
-// 	in := make(chan canopus.ObserveMessage, 16)
-// 	go c.connection.Observe(in)
-// 	go c.observer(in, out)
-// 	return out
-// }
-
-// func (c *Client) Observe(deviceId int) error {
-// 	uri := fmt.Sprintf("%s/%d", uriDevices, deviceId)
-// 	_, err := c.connection.ObserveResource(uri)
-// 	return err
-// }
+	return c.putRequest(ctx, uri, payload)
+}
+
+// CreateGroup creates a group containing deviceIDs, returning its id.
+func (c *Client) CreateGroup(name string, deviceIDs []int) (int, error) {
+	return c.CreateGroupContext(context.Background(), name, deviceIDs)
+}
+
+func (c *Client) CreateGroupContext(ctx context.Context, name string, deviceIDs []int) (int, error) {
+	if name == "" {
+		return 0, errors.New("Name must not be empty")
+	}
+	if deviceIDs == nil {
+		// the gateway expects a list, not null
+		deviceIDs = []int{}
+	}
+	payload := GroupCreate{GroupName: name}
+	payload.AccessoryLink.LinkedItems.DeviceIDs = deviceIDs
+	var created GroupCreated
+	err := c.postRequest(ctx, uriGroups, payload, &created)
+	if err != nil {
+		return 0, err
+	}
+	return created.GroupID, nil
+}
+
+// RenameGroup changes the name of a group.
+func (c *Client) RenameGroup(groupId int, name string) error {
+	return c.RenameGroupContext(context.Background(), groupId, name)
+}
+
+func (c *Client) RenameGroupContext(ctx context.Context, groupId int, name string) error {
+	if name == "" {
+		return errors.New("Name must not be empty")
+	}
+	uri := fmt.Sprintf("%s/%d", uriGroups, groupId)
+	return c.putRequest(ctx, uri, NameSet{name})
+}
+
+// DeleteGroup deletes a group. Its devices are not affected.
+func (c *Client) DeleteGroup(groupId int) error {
+	return c.DeleteGroupContext(context.Background(), groupId)
+}
+
+func (c *Client) DeleteGroupContext(ctx context.Context, groupId int) error {
+	uri := fmt.Sprintf("%s/%d", uriGroups, groupId)
+	return c.deleteRequest(ctx, uri)
+}
+
+// AddToGroup adds devices to a group, keeping its existing members.
+func (c *Client) AddToGroup(groupId int, deviceIDs ...int) error {
+	return c.AddToGroupContext(context.Background(), groupId, deviceIDs...)
+}
+
+func (c *Client) AddToGroupContext(ctx context.Context, groupId int, deviceIDs ...int) error {
+	return c.updateGroupMembers(ctx, groupId, func(members map[int]bool) {
+		for _, id := range deviceIDs {
+			members[id] = true
+		}
+	})
+}
+
+// RemoveFromGroup removes devices from a group.
+func (c *Client) RemoveFromGroup(groupId int, deviceIDs ...int) error {
+	return c.RemoveFromGroupContext(context.Background(), groupId, deviceIDs...)
+}
+
+func (c *Client) RemoveFromGroupContext(ctx context.Context, groupId int, deviceIDs ...int) error {
+	return c.updateGroupMembers(ctx, groupId, func(members map[int]bool) {
+		for _, id := range deviceIDs {
+			delete(members, id)
+		}
+	})
+}
+
+// updateGroupMembers reads the members of a group, applies update and writes
+// back the resulting list.
+func (c *Client) updateGroupMembers(ctx context.Context, groupId int, update func(map[int]bool)) error {
+	group, err := c.GetGroupDescriptionContext(ctx, groupId)
+	if err != nil {
+		return err
+	}
+	members := map[int]bool{}
+	for _, id := range group.AccessoryLink.LinkedItems.DeviceIDs {
+		members[id] = true
+	}
+	update(members)
+	ids := []int{}
+	for id := range members {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var payload GroupMembersSet
+	payload.AccessoryLink.LinkedItems.DeviceIDs = ids
+	uri := fmt.Sprintf("%s/%d", uriGroups, groupId)
+	return c.putRequest(ctx, uri, payload)
+}
+
+func (c *Client) observer(uri string, in <-chan coap.Message) {
+	if uri == uriNotifications {
+		c.notificationObserver(in)
+		return
+	}
+	for msg := range in {
+		if err := responseError(msg); err != nil {
+			log.Printf("Error observing %s: %s", uri, err)
+			continue
+		}
+		var out interface{}
+		if strings.HasPrefix(uri, uriGroups+"/") {
+			out = &GroupDescription{}
+		} else {
+			out = &DeviceDescription{}
+		}
+		err := json.Unmarshal(msg.Payload, out)
+		if err != nil {
+			log.Printf("Error decoding %s: %s", uri, err)
+			continue
+		}
+		c.emit(out)
+	}
+}
+
+// Events returns a channel of updates to observed resources. Values are
+// *DeviceDescription, *GroupDescription or *Notification. The channel is
+// closed once the Client has been closed and its observations have stopped;
+// updates arriving after Close may be dropped.
+func (c *Client) Events() <-chan interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	return c.events
+}
+
+// startObserver runs observer for in, unless the Client has been closed.
+func (c *Client) startObserver(uri string, in <-chan coap.Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	select {
+	case <-c.closed:
+		return false
+	default:
+	}
+	c.observers.Add(1)
+	go func() {
+		defer c.observers.Done()
+		c.observer(uri, in)
+	}()
+	return true
+}
+
+// emit sends an event, giving up once the Client is closed so an observer
+// never blocks on a reader that has gone.
+func (c *Client) emit(event interface{}) {
+	select {
+	case c.events <- event:
+	case <-c.closed:
+	}
+}
+
+// Observe subscribes to changes of a device, group or notifications resource,
+// delivering them on Events. Observations are re-registered automatically
+// after a reconnect.
+func (c *Client) Observe(uri string) error {
+	return c.ObserveContext(context.Background(), uri)
+}
+
+func (c *Client) ObserveContext(ctx context.Context, uri string) error {
+	t, err := c.conn(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.limiter.wait(ctx); err != nil {
+		return err
+	}
+	in, err := t.ObserveContext(ctx, uri)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.observed = append(c.observed, uri)
+	c.mu.Unlock()
+	if !c.startObserver(uri, in) {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (c *Client) ObserveDevice(deviceId int) error {
+	return c.ObserveDeviceContext(context.Background(), deviceId)
+}
+
+func (c *Client) ObserveDeviceContext(ctx context.Context, deviceId int) error {
+	return c.ObserveContext(ctx, fmt.Sprintf("%s/%d", uriDevices, deviceId))
+}
+
+func (c *Client) ObserveGroup(groupId int) error {
+	return c.ObserveGroupContext(context.Background(), groupId)
+}
+
+func (c *Client) ObserveGroupContext(ctx context.Context, groupId int) error {
+	return c.ObserveContext(ctx, fmt.Sprintf("%s/%d", uriGroups, groupId))
+}
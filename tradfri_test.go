@@ -0,0 +1,793 @@
+package tradfri_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/barnybug/go-tradfri/tradfritest"
+	"github.com/dustin/go-coap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastTransmission = tradfri.TransmissionParams{
+	AckTimeout:      100 * time.Millisecond,
+	AckRandomFactor: 1,
+	MaxRetransmit:   1,
+}
+
+func setup(t *testing.T) (*tradfritest.Server, *tradfri.Client) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	require.NoError(t, server.SetDevice(65536, tradfritest.NewLight(65536, "Kitchen")))
+	require.NoError(t, server.SetDevice(65537, tradfritest.NewLight(65537, "Hall")))
+	require.NoError(t, server.SetGroup(131072, tradfritest.NewGroup(131072, "Downstairs", 65536, 65537)))
+
+	client := tradfri.NewClient(server.Addr())
+	client.Key = server.Key
+	client.Transmission = fastTransmission
+	client.ReconnectBackoff = 10 * time.Millisecond
+	client.ReconnectAttempts = 3
+	require.NoError(t, client.Connect())
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestConnectIssuesPSK(t *testing.T) {
+	_, client := setup(t)
+	assert.NotEmpty(t, client.Ident)
+	assert.NotEmpty(t, client.PSK)
+}
+
+func TestConnectWrongKey(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := tradfri.NewClient(server.Addr())
+	client.Key = "wrongcode"
+	client.Transmission = fastTransmission
+	err = client.Connect()
+	assert.True(t, errors.Is(err, tradfri.ErrUnauthorized))
+	assert.Empty(t, client.PSK)
+}
+
+func TestListDevices(t *testing.T) {
+	_, client := setup(t)
+	devices, err := client.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "Kitchen", devices[0].DeviceName)
+	assert.Equal(t, 65537, devices[1].DeviceID)
+}
+
+func TestListDevicesConcurrent(t *testing.T) {
+	server, client := setup(t)
+	for id := 65538; id < 65542; id++ {
+		require.NoError(t, server.SetDevice(id, tradfritest.NewLight(id, "Light")))
+	}
+
+	devices, err := client.ListDevicesConcurrent(4)
+	require.NoError(t, err)
+	require.Len(t, devices, 6)
+	for i, device := range devices {
+		assert.Equal(t, 65536+i, device.DeviceID)
+	}
+}
+
+// failingTransport fails requests for one path.
+type failingTransport struct {
+	tradfri.Transport
+	path string
+}
+
+func (f failingTransport) Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error) {
+	if path == f.path {
+		return coap.Message{Type: coap.Acknowledgement, Code: coap.InternalServerError}, nil
+	}
+	return f.Transport.Do(ctx, method, path, payload)
+}
+
+func TestListDevicesConcurrentPartial(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+	require.NoError(t, server.SetDevice(65536, tradfritest.NewLight(65536, "Kitchen")))
+	require.NoError(t, server.SetDevice(65537, tradfritest.NewLight(65537, "Hall")))
+
+	client := tradfri.NewClient("")
+	client.Dial = func() (tradfri.Transport, error) {
+		return failingTransport{server.Loopback(), "/15001/65536"}, nil
+	}
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	devices, err := client.ListDevicesConcurrent(2)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "Hall", devices[0].DeviceName)
+	assert.True(t, errors.Is(err, tradfri.ErrInternalServerError))
+	var multi tradfri.MultiError
+	require.True(t, errors.As(err, &multi))
+	require.Len(t, multi, 1)
+	assert.Equal(t, 65536, multi[0].(*tradfri.DeviceError).ID)
+}
+
+func TestGetDeviceNotFound(t *testing.T) {
+	_, client := setup(t)
+	_, err := client.GetDeviceDescription(1)
+	assert.True(t, errors.Is(err, tradfri.ErrNotFound))
+}
+
+func TestSetDevice(t *testing.T) {
+	server, client := setup(t)
+	power := 0
+	require.NoError(t, client.SetDevice(65536, tradfri.LightControl{Power: &power}))
+
+	var desc tradfri.DeviceDescription
+	require.NoError(t, server.Device(65536, &desc))
+	assert.Equal(t, 0, *desc.LightControl[0].Power)
+	assert.Equal(t, tradfri.DimMax, *desc.LightControl[0].Dim)
+}
+
+func TestPlug(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, server.SetDevice(65541, tradfritest.NewPlug(65541, "Heater")))
+	require.NoError(t, client.SetPlug(65541, false))
+
+	desc, err := client.GetDeviceDescription(65541)
+	require.NoError(t, err)
+	require.Len(t, desc.PlugControl, 1)
+	assert.Equal(t, 0, *desc.PlugControl[0].Power)
+	assert.Contains(t, desc.String(), "Plug Control Set 0, Power: off")
+}
+
+func TestAirPurifier(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, server.SetDevice(65542, tradfritest.NewAirPurifier(65542, "Lounge")))
+	require.NoError(t, client.SetAirPurifierMode(65542, tradfri.FanModeOff))
+	require.NoError(t, client.SetAirPurifierSpeed(65542, 30))
+	require.NoError(t, client.SetAirPurifierChildLock(65542, true))
+	require.NoError(t, client.SetAirPurifierLEDs(65542, false))
+	assert.Error(t, client.SetAirPurifierMode(65542, 5))
+	assert.Error(t, client.SetAirPurifierSpeed(65542, 51))
+
+	desc, err := client.GetDeviceDescription(65542)
+	require.NoError(t, err)
+	require.Len(t, desc.AirPurifierControl, 1)
+	ap := desc.AirPurifierControl[0]
+	assert.Equal(t, tradfri.FanModeOff, *ap.FanMode)
+	assert.Equal(t, 30, *ap.FanSpeed)
+	assert.Equal(t, 1, *ap.ChildLock)
+	assert.Equal(t, 1, *ap.LEDDisable)
+	assert.Contains(t, desc.String(), "Mode: off, Fan speed: 30, PM2.5: 5, Filter: 0/259200 min, Child lock: on, LEDs: off")
+}
+
+func TestBlind(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, server.SetDevice(65540, tradfritest.NewBlind(65540, "Bedroom")))
+	require.NoError(t, client.SetBlindPosition(65540, 75))
+	require.NoError(t, client.StopBlind(65540))
+	assert.Error(t, client.SetBlindPosition(65540, 101))
+
+	desc, err := client.GetDeviceDescription(65540)
+	require.NoError(t, err)
+	require.Len(t, desc.BlindControl, 1)
+	assert.Equal(t, 75.0, *desc.BlindControl[0].Position)
+	assert.Equal(t, 0, *desc.BlindControl[0].Trigger)
+	assert.Contains(t, desc.String(), "Blind Control Set 0, Position: 75%")
+}
+
+func TestSetDeviceChannel(t *testing.T) {
+	server, client := setup(t)
+	fixture := tradfritest.NewLight(65543, "Chandelier")
+	fixture.LightControl = append(fixture.LightControl, fixture.LightControl[0], fixture.LightControl[0])
+	require.NoError(t, server.SetDevice(65543, fixture))
+
+	power := 0
+	require.NoError(t, client.SetDeviceChannel(65543, 1, tradfri.LightControl{Power: &power}))
+	assert.Error(t, client.SetDeviceChannel(65543, 3, tradfri.LightControl{Power: &power}))
+
+	var desc tradfri.DeviceDescription
+	require.NoError(t, server.Device(65543, &desc))
+	require.Len(t, desc.LightControl, 3)
+	assert.Equal(t, 1, *desc.LightControl[0].Power)
+	assert.Equal(t, 0, *desc.LightControl[1].Power)
+	assert.Equal(t, 1, *desc.LightControl[2].Power)
+
+	channel, err := client.GetDeviceChannel(65543, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, *channel.Power)
+	assert.Equal(t, tradfri.DimMax, *channel.Dim)
+}
+
+// recordingTransport records the payloads of PUT requests.
+type recordingTransport struct {
+	tradfri.Transport
+	puts chan []byte
+}
+
+func (r recordingTransport) Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error) {
+	if method == coap.PUT {
+		r.puts <- payload
+	}
+	return r.Transport.Do(ctx, method, path, payload)
+}
+
+func TestSetDeviceChannelSendsOtherChannels(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+	fixture := tradfritest.NewLight(65543, "Chandelier")
+	power, dims := 1, []int{10, 20, 30}
+	fixture.LightControl = nil
+	for i := range dims {
+		fixture.LightControl = append(fixture.LightControl, tradfri.LightControl{Power: &power, Dim: &dims[i]})
+	}
+	require.NoError(t, server.SetDevice(65543, fixture))
+
+	puts := make(chan []byte, 1)
+	client := tradfri.NewClient("")
+	client.Dial = func() (tradfri.Transport, error) {
+		return recordingTransport{server.Loopback(), puts}, nil
+	}
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	off := 0
+	require.NoError(t, client.SetDeviceChannel(65543, 1, tradfri.LightControl{Power: &off}))
+	var sent tradfri.DeviceSet
+	require.NoError(t, json.Unmarshal(<-puts, &sent))
+	require.Len(t, sent.LightControl, 3)
+	assert.Equal(t, fixture.LightControl[0], sent.LightControl[0])
+	assert.Equal(t, tradfri.LightControl{Power: &off}, sent.LightControl[1])
+	assert.Equal(t, fixture.LightControl[2], sent.LightControl[2])
+}
+
+func TestRenameDevice(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, client.RenameDevice(65536, "Larder"))
+	assert.Error(t, client.RenameDevice(65536, ""))
+
+	var desc tradfri.DeviceDescription
+	require.NoError(t, server.Device(65536, &desc))
+	assert.Equal(t, "Larder", desc.DeviceName)
+}
+
+func TestRemoveDevice(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, client.RemoveDevice(65536))
+	assert.True(t, errors.Is(client.RemoveDevice(65536), tradfri.ErrNotFound))
+
+	ids, err := client.ListDeviceIds()
+	require.NoError(t, err)
+	assert.Equal(t, []int{65537}, ids)
+	var group tradfri.GroupDescription
+	require.NoError(t, server.Group(131072, &group))
+	assert.Equal(t, []int{65537}, group.AccessoryLink.LinkedItems.DeviceIDs)
+}
+
+func TestSetGroup(t *testing.T) {
+	server, client := setup(t)
+	dim := 100
+	require.NoError(t, client.SetGroup(131072, tradfri.LightControl{Dim: &dim}))
+
+	var desc tradfri.DeviceDescription
+	require.NoError(t, server.Device(65537, &desc))
+	assert.Equal(t, 100, *desc.LightControl[0].Dim)
+}
+
+func TestGroupManagement(t *testing.T) {
+	server, client := setup(t)
+	id, err := client.CreateGroup("Upstairs", []int{65536})
+	require.NoError(t, err)
+	assert.Equal(t, 131073, id)
+
+	require.NoError(t, client.RenameGroup(id, "Landing"))
+	require.NoError(t, client.AddToGroup(id, 65537, 65536))
+	group, err := client.GetGroupDescription(id)
+	require.NoError(t, err)
+	assert.Equal(t, "Landing", group.GroupName)
+	assert.Equal(t, []int{65536, 65537}, group.AccessoryLink.LinkedItems.DeviceIDs)
+
+	require.NoError(t, client.RemoveFromGroup(id, 65536))
+	require.NoError(t, server.Group(id, group))
+	assert.Equal(t, []int{65537}, group.AccessoryLink.LinkedItems.DeviceIDs)
+
+	require.NoError(t, client.DeleteGroup(id))
+	ids, err := client.ListGroupIds()
+	require.NoError(t, err)
+	assert.Equal(t, []int{131072}, ids)
+}
+
+func TestCreateEmptyGroup(t *testing.T) {
+	_, client := setup(t)
+	id, err := client.CreateGroup("Empty", nil)
+	require.NoError(t, err)
+	group, err := client.GetGroupDescription(id)
+	require.NoError(t, err)
+	assert.Empty(t, group.AccessoryLink.LinkedItems.DeviceIDs)
+}
+
+func TestMoods(t *testing.T) {
+	server, client := setup(t)
+	dim := 100
+	require.NoError(t, client.SetDevice(65537, tradfri.LightControl{Dim: &dim}))
+	color, hue, sat := "f1e0b5", 5000, 40000
+	require.NoError(t, client.SetDevice(65536, tradfri.LightControl{Color: &color, ColorHue: &hue, ColorSat: &sat}))
+	id, err := client.CreateMood(131072, "Evening")
+	require.NoError(t, err)
+
+	moods, err := client.ListMoods(131072)
+	require.NoError(t, err)
+	require.Len(t, moods, 1)
+	assert.Equal(t, "Evening", moods[0].Name)
+	require.Len(t, moods[0].LightSettings, 2)
+	assert.Equal(t, 65537, moods[0].LightSettings[1].DeviceID)
+	assert.Equal(t, 100, *moods[0].LightSettings[1].Dim)
+	colored := moods[0].LightSettings[0]
+	require.NotNil(t, colored.Color)
+	assert.Equal(t, "f1e0b5", *colored.Color)
+	require.NotNil(t, colored.ColorHue)
+	assert.Equal(t, 5000, *colored.ColorHue)
+	require.NotNil(t, colored.ColorSat)
+	assert.Equal(t, 40000, *colored.ColorSat)
+
+	full := tradfri.DimMax
+	require.NoError(t, client.SetGroup(131072, tradfri.LightControl{Dim: &full}))
+	require.NoError(t, client.ActivateMood(131072, id))
+	var desc tradfri.DeviceDescription
+	require.NoError(t, server.Device(65537, &desc))
+	assert.Equal(t, 100, *desc.LightControl[0].Dim)
+	group, err := client.GetGroupDescription(131072)
+	require.NoError(t, err)
+	assert.Equal(t, id, group.ActiveMood)
+
+	require.NoError(t, client.DeleteMood(131072, id))
+	ids, err := client.ListMoodIds(131072)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	_, err = client.ListMoodIds(131099)
+	assert.True(t, errors.Is(err, tradfri.ErrNotFound))
+}
+
+func TestSmartTasks(t *testing.T) {
+	_, client := setup(t)
+	dim, duration := tradfri.DimMax, 9000
+	task := tradfri.SmartTask{
+		Enabled:    1,
+		Type:       tradfri.TaskWakeUp,
+		RepeatDays: tradfri.WorkingDays,
+		StartAction: tradfri.StartAction{
+			Power: 1,
+			LightSettings: []tradfri.LightSetting{
+				{DeviceID: 65536, LightControl: tradfri.LightControl{Dim: &dim, Duration: &duration}},
+			},
+		},
+		Intervals: []tradfri.TimeInterval{{StartHour: 6, StartMinute: 30}},
+	}
+	id, err := client.CreateSmartTask(task)
+	require.NoError(t, err)
+	_, err = client.CreateSmartTask(tradfri.SmartTask{Type: tradfri.TaskOnOff})
+	assert.Error(t, err)
+	invalid := task
+	invalid.RepeatDays = 128
+	_, err = client.CreateSmartTask(invalid)
+	assert.Error(t, err)
+
+	tasks, err := client.ListSmartTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, id, tasks[0].TaskID)
+	assert.Equal(t, 1, tasks[0].Enabled)
+	assert.Equal(t, "ID: 327680 Type: Wake up Enabled: true Repeat: weekdays Time: 06:30\n"+
+		"Device: 65536 Dim: 100% Transition: 15m0s\n", tasks[0].String())
+
+	task = *tasks[0]
+	task.RepeatDays = tradfri.Saturday
+	task.Intervals[0].StartHour = 8
+	require.NoError(t, client.UpdateSmartTask(task))
+	require.NoError(t, client.EnableSmartTask(id, false))
+	updated, err := client.GetSmartTask(id)
+	require.NoError(t, err)
+	assert.Equal(t, tradfri.Saturday, updated.RepeatDays)
+	assert.Equal(t, 8, updated.Intervals[0].StartHour)
+	assert.Equal(t, 0, updated.Enabled)
+
+	require.NoError(t, client.DeleteSmartTask(id))
+	ids, err := client.ListSmartTaskIds()
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestNotAtHomeSmartTask(t *testing.T) {
+	_, client := setup(t)
+	end := 0
+	id, err := client.CreateSmartTask(tradfri.SmartTask{
+		Enabled:    1,
+		Type:       tradfri.TaskNotAtHome,
+		RepeatDays: tradfri.EveryDay,
+		Intervals:  []tradfri.TimeInterval{{StartHour: 18, EndHour: &end, EndMinute: &end}},
+	})
+	require.NoError(t, err)
+	task, err := client.GetSmartTask(id)
+	require.NoError(t, err)
+	require.NotNil(t, task.Intervals[0].EndHour)
+	assert.Equal(t, "18:00-00:00", task.Intervals[0].String())
+}
+
+func TestObserve(t *testing.T) {
+	_, client := setup(t)
+	require.NoError(t, client.ObserveDevice(65536))
+	initial := nextEvent(t, client).(*tradfri.DeviceDescription)
+	assert.Equal(t, 1, *initial.LightControl[0].Power)
+
+	power := 0
+	require.NoError(t, client.SetDevice(65536, tradfri.LightControl{Power: &power}))
+	changed := nextEvent(t, client).(*tradfri.DeviceDescription)
+	assert.Equal(t, 0, *changed.LightControl[0].Power)
+}
+
+func TestNotifications(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, server.AddNotification(&tradfri.Notification{
+		Event:     tradfri.NotificationGatewayReboot,
+		Details:   []string{"reason=2"},
+		CreatedAt: 1545049021,
+	}))
+	require.NoError(t, client.ObserveNotifications())
+
+	require.NoError(t, server.AddNotification(&tradfri.Notification{
+		Event:     tradfri.NotificationNewFirmware,
+		CreatedAt: 1545049022,
+	}))
+	n := nextEvent(t, client).(*tradfri.Notification)
+	assert.Equal(t, tradfri.NotificationNewFirmware, n.Event)
+
+	notifications, err := client.ListNotifications()
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "2", notifications[0].Detail("reason"))
+	assert.Equal(t, "Gateway rebooted", notifications[0].Event.String())
+	assert.Contains(t, notifications[0].String(), "Gateway rebooted reason=2")
+}
+
+func TestNotificationsAfterReconnect(t *testing.T) {
+	server, client := setup(t)
+	require.NoError(t, client.ObserveNotifications())
+	require.NoError(t, server.AddNotification(&tradfri.Notification{
+		Event:     tradfri.NotificationNewFirmware,
+		CreatedAt: 1545049021,
+	}))
+	nextEvent(t, client)
+
+	server.DropSessions()
+	require.NoError(t, server.AddNotification(&tradfri.Notification{
+		Event:     tradfri.NotificationGatewayReboot,
+		CreatedAt: 1545049022,
+	}))
+	_, err := client.ListDeviceIds()
+	require.NoError(t, err)
+	n := nextEvent(t, client).(*tradfri.Notification)
+	assert.Equal(t, tradfri.NotificationGatewayReboot, n.Event)
+}
+
+func TestCommission(t *testing.T) {
+	server, client := setup(t)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		server.SetDevice(65544, tradfritest.NewLight(65544, "New"))
+	}()
+	var found []int
+	added, err := client.Commission(time.Second, func(id int) { found = append(found, id) })
+	require.NoError(t, err)
+	assert.Equal(t, []int{65544}, added)
+	assert.Equal(t, added, found)
+
+	var info map[string]interface{}
+	require.NoError(t, server.Resource("15011/15012", &info))
+	assert.Equal(t, 1.0, info["9061"])
+}
+
+func TestCommissionCancel(t *testing.T) {
+	server, client := setup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(400 * time.Millisecond)
+		server.SetDevice(65544, tradfritest.NewLight(65544, "New"))
+		cancel()
+	}()
+	// polls are a second apart, so only a final poll finds the device
+	added, err := client.CommissionContext(ctx, 10*time.Second, nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []int{65544}, added)
+
+	var info map[string]interface{}
+	require.NoError(t, server.Resource("15011/15012", &info))
+	assert.Equal(t, 0.0, info["9061"])
+}
+
+func TestGatewaySettings(t *testing.T) {
+	_, client := setup(t)
+	require.NoError(t, client.SetNTPServer("time.example.com"))
+	assert.Error(t, client.SetNTPServer(""))
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	require.NoError(t, client.SetTimezone(london))
+
+	info, err := client.GetGatewayInfo()
+	require.NoError(t, err)
+	assert.Equal(t, "time.example.com", info.NTPServer)
+	assert.Equal(t, 60, info.Offset)
+	assert.Equal(t, 3, info.StartMonth)
+	assert.Contains(t, info.Details(), "Daylight Saving: +60min from 03-")
+	assert.Contains(t, info.Details(), "UTC Offset: 0min")
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	require.NoError(t, client.SetTimezone(berlin))
+	info, err = client.GetGatewayInfo()
+	require.NoError(t, err)
+	assert.Equal(t, 60, info.UTCOffset)
+	assert.Equal(t, 60, info.Offset)
+	assert.Contains(t, info.Details(), "UTC Offset: 60min")
+}
+
+func TestFirmwareReport(t *testing.T) {
+	server, client := setup(t)
+	light := tradfritest.NewLight(65537, "Hall")
+	light.OTAUpdateState = tradfri.OTADownloading
+	require.NoError(t, server.SetDevice(65537, light))
+	require.NoError(t, client.CheckForUpdates())
+
+	report, err := client.GetFirmwareReport()
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", report.Gateway.FirmwareVersion)
+	require.Len(t, report.Devices, 2)
+	assert.Equal(t, "Light", report.Devices[0].Type)
+	assert.Equal(t, tradfri.OTAUpToDate, report.Devices[0].State)
+	updating := report.Updating()
+	require.Len(t, updating, 1)
+	assert.Equal(t, 65537, updating[0].DeviceID)
+	assert.Equal(t, "Downloading", updating[0].State.String())
+
+	var info map[string]interface{}
+	require.NoError(t, server.Resource("15011/15012", &info))
+	assert.Equal(t, 1.0, info["9032"])
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	_, client := setup(t)
+	errs := make(chan error)
+	for i := 0; i < 10; i++ {
+		go func(id int) {
+			desc, err := client.GetDeviceDescription(id)
+			if err == nil && desc.DeviceID != id {
+				err = errors.New("mismatched response")
+			}
+			errs <- err
+		}(65536 + i%2)
+	}
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	server, client := setup(t)
+	var states []tradfri.ConnState
+	client.OnStateChange = func(s tradfri.ConnState) { states = append(states, s) }
+	require.NoError(t, client.ObserveDevice(65536))
+	nextEvent(t, client)
+
+	server.DropSessions()
+	_, err := client.GetDeviceDescription(65536)
+	require.NoError(t, err)
+	assert.Equal(t, []tradfri.ConnState{tradfri.StateReconnecting, tradfri.StateConnected}, states)
+	// the re-registered observation delivers the current state
+	nextEvent(t, client)
+}
+
+func nextEvent(t *testing.T, client *tradfri.Client) interface{} {
+	select {
+	case event := <-client.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return nil
+}
+
+func TestReconnectHonoursContext(t *testing.T) {
+	server, client := setup(t)
+	_, err := client.ListDeviceIds()
+	require.NoError(t, err)
+	client.Dial = func() (tradfri.Transport, error) { return nil, errors.New("unreachable") }
+	client.ReconnectAttempts = 10
+	client.ReconnectBackoff = time.Minute
+	server.DropSessions()
+
+	// the lost session is noticed, then the context expires while the
+	// reconnection backs off
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err = client.ListDeviceIdsContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(2*time.Second))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = client.ListDeviceIdsContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	start = time.Now()
+	require.NoError(t, client.Close())
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	_, err = client.ListDeviceIds()
+	assert.Equal(t, tradfri.ErrClosed, err)
+}
+
+func TestKeepAliveAfterReconnectGivesUp(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+	require.NoError(t, server.SetDevice(65536, tradfritest.NewLight(65536, "Kitchen")))
+	server.AddIdent("ident", "psk")
+
+	var unreachable int32
+	client := tradfri.NewClient(server.Addr())
+	client.Ident = "ident"
+	client.PSK = "psk"
+	client.Dial = func() (tradfri.Transport, error) {
+		if atomic.LoadInt32(&unreachable) != 0 {
+			return nil, errors.New("unreachable")
+		}
+		dc, err := tradfri.NewDtlsClient(server.Addr(), client.Ident, client.PSK)
+		if err != nil {
+			return nil, err
+		}
+		dc.Params = fastTransmission
+		return dc, nil
+	}
+	client.ReconnectBackoff = 10 * time.Millisecond
+	client.ReconnectAttempts = 2
+	client.KeepAlive = 100 * time.Millisecond
+	states := make(chan tradfri.ConnState, 10)
+	client.OnStateChange = func(s tradfri.ConnState) { states <- s }
+	require.NoError(t, client.Connect())
+	defer client.Close()
+	require.NoError(t, client.ObserveDevice(65536))
+	nextEvent(t, client)
+
+	// the gateway stays away for longer than the reconnection attempts
+	atomic.StoreInt32(&unreachable, 1)
+	server.DropSessions()
+	for s := range states {
+		if s == tradfri.StateDisconnected {
+			break
+		}
+	}
+	atomic.StoreInt32(&unreachable, 0)
+
+	// the next keepalive reconnects and re-registers the observation
+	nextEvent(t, client)
+	assert.Equal(t, tradfri.StateConnected, client.State())
+}
+
+// lostTransport times out requests for one path, as if the session had been
+// lost.
+type lostTransport struct {
+	tradfri.Transport
+	path string
+}
+
+func (l lostTransport) Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error) {
+	if path == l.path {
+		return coap.Message{}, tradfri.ErrTimeout
+	}
+	return l.Transport.Do(ctx, method, path, payload)
+}
+
+// stallingTransport never completes an observation request.
+type stallingTransport struct {
+	tradfri.Transport
+	cancelled chan struct{}
+}
+
+func (s stallingTransport) ObserveContext(ctx context.Context, path string) (<-chan coap.Message, error) {
+	<-ctx.Done()
+	close(s.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestReconnectWhileObservationsStall(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+	require.NoError(t, server.SetDevice(65536, tradfritest.NewLight(65536, "Kitchen")))
+
+	cancelled := make(chan struct{})
+	var dials int32
+	client := tradfri.NewClient("")
+	client.Dial = func() (tradfri.Transport, error) {
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return lostTransport{server.Loopback(), "/15001/65536"}, nil
+		}
+		return stallingTransport{server.Loopback(), cancelled}, nil
+	}
+	require.NoError(t, client.Connect())
+	require.NoError(t, client.ObserveDevice(65536))
+	nextEvent(t, client)
+
+	// requests and the connection state do not wait for re-observation
+	_, err = client.GetDeviceDescription(65536)
+	require.NoError(t, err)
+	assert.Equal(t, tradfri.StateConnected, client.State())
+
+	require.NoError(t, client.Close())
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("re-observation not cancelled by Close")
+	}
+}
+
+func TestEventsClosedAfterClose(t *testing.T) {
+	_, client := setup(t)
+	require.NoError(t, client.ObserveDevice(65536))
+	require.NoError(t, client.ObserveNotifications())
+
+	done := make(chan struct{})
+	go func() {
+		for range client.Events() {
+		}
+		close(done)
+	}()
+	require.NoError(t, client.Close())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Events not closed")
+	}
+}
+
+func TestClientWithoutNewClient(t *testing.T) {
+	client := &tradfri.Client{}
+	assert.NotNil(t, client.Events())
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close())
+}
+
+func TestLoopback(t *testing.T) {
+	server, err := tradfritest.NewServer("securitycode")
+	require.NoError(t, err)
+	defer server.Close()
+	require.NoError(t, server.SetDevice(65536, tradfritest.NewLight(65536, "Kitchen")))
+	require.NoError(t, server.SetGroup(131072, tradfritest.NewGroup(131072, "Downstairs", 65536)))
+
+	client := tradfri.NewClient("")
+	client.Dial = func() (tradfri.Transport, error) { return server.Loopback(), nil }
+	require.NoError(t, client.Connect())
+	defer client.Close()
+
+	require.NoError(t, client.ObserveDevice(65536))
+	nextEvent(t, client)
+
+	dim := 10
+	require.NoError(t, client.SetGroup(131072, tradfri.LightControl{Dim: &dim}))
+	changed := nextEvent(t, client).(*tradfri.DeviceDescription)
+	assert.Equal(t, 10, *changed.LightControl[0].Dim)
+
+	devices, err := client.ListDevices()
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, 10, *devices[0].LightControl[0].Dim)
+}
@@ -0,0 +1,114 @@
+package tradfritest
+
+import (
+	"time"
+
+	tradfri "github.com/barnybug/go-tradfri"
+)
+
+// NewLight returns a reachable, dimmable white spectrum bulb that is on at
+// full brightness.
+func NewLight(id int, name string) *tradfri.DeviceDescription {
+	power, dim, mireds := 1, tradfri.DimMax, tradfri.MiredMin
+	d := &tradfri.DeviceDescription{
+		LightControl: []tradfri.LightControl{{
+			Power:  &power,
+			Dim:    &dim,
+			Mireds: &mireds,
+		}},
+		ApplicationType:   tradfri.Lamp,
+		DeviceName:        name,
+		CreatedAt:         int(time.Now().Unix()),
+		DeviceID:          id,
+		ReachabilityState: 1,
+		LastSeen:          int(time.Now().Unix()),
+	}
+	d.Device.Manufacturer = "IKEA of Sweden"
+	d.Device.ModelNumber = "TRADFRI bulb E27 WS opal 980lm"
+	d.Device.FirmwareVersion = "1.2.214"
+	d.Device.AvailablePowerSources = 6
+	return d
+}
+
+// NewPlug returns a reachable control outlet that is switched on.
+func NewPlug(id int, name string) *tradfri.DeviceDescription {
+	power := 1
+	d := &tradfri.DeviceDescription{
+		PlugControl:       []tradfri.PlugControl{{Power: &power}},
+		ApplicationType:   tradfri.Plug,
+		DeviceName:        name,
+		CreatedAt:         int(time.Now().Unix()),
+		DeviceID:          id,
+		ReachabilityState: 1,
+		LastSeen:          int(time.Now().Unix()),
+	}
+	d.Device.Manufacturer = "IKEA of Sweden"
+	d.Device.ModelNumber = "TRADFRI control outlet"
+	d.Device.FirmwareVersion = "2.0.024"
+	d.Device.AvailablePowerSources = 6
+	return d
+}
+
+// NewAirPurifier returns a reachable air purifier in auto mode with a fresh
+// filter.
+func NewAirPurifier(id int, name string) *tradfri.DeviceDescription {
+	mode, speed, quality, zero, lifetime := tradfri.FanModeAuto, 10, 5, 0, 259200
+	d := &tradfri.DeviceDescription{
+		AirPurifierControl: []tradfri.AirPurifierControl{{
+			FanMode:         &mode,
+			FanSpeed:        &speed,
+			AirQuality:      &quality,
+			FilterRuntime:   &zero,
+			FilterLifetime:  &lifetime,
+			FilterRemaining: &lifetime,
+			FilterStatus:    &zero,
+			ChildLock:       &zero,
+			LEDDisable:      &zero,
+			MotorRuntime:    &zero,
+		}},
+		ApplicationType:   tradfri.AirPurifier,
+		DeviceName:        name,
+		CreatedAt:         int(time.Now().Unix()),
+		DeviceID:          id,
+		ReachabilityState: 1,
+		LastSeen:          int(time.Now().Unix()),
+	}
+	d.Device.Manufacturer = "IKEA of Sweden"
+	d.Device.ModelNumber = "STARKVIND Air purifier"
+	d.Device.FirmwareVersion = "1.0.033"
+	d.Device.AvailablePowerSources = 6
+	return d
+}
+
+// NewBlind returns a reachable, fully open battery powered roller blind.
+func NewBlind(id int, name string) *tradfri.DeviceDescription {
+	position := 0.0
+	d := &tradfri.DeviceDescription{
+		BlindControl:      []tradfri.BlindControl{{Position: &position}},
+		ApplicationType:   tradfri.Blind,
+		DeviceName:        name,
+		CreatedAt:         int(time.Now().Unix()),
+		DeviceID:          id,
+		ReachabilityState: 1,
+		LastSeen:          int(time.Now().Unix()),
+	}
+	d.Device.Manufacturer = "IKEA of Sweden"
+	d.Device.ModelNumber = "FYRTUR block-out roller blind"
+	d.Device.FirmwareVersion = "2.2.009"
+	d.Device.AvailablePowerSources = 3
+	d.Device.BatteryLevel = 87
+	return d
+}
+
+// NewGroup returns a group containing deviceIDs.
+func NewGroup(id int, name string, deviceIDs ...int) *tradfri.GroupDescription {
+	g := &tradfri.GroupDescription{
+		Power:     1,
+		Dim:       tradfri.DimMax,
+		GroupName: name,
+		CreatedAt: int(time.Now().Unix()),
+		GroupID:   id,
+	}
+	g.AccessoryLink.LinkedItems.DeviceIDs = deviceIDs
+	return g
+}
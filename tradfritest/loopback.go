@@ -0,0 +1,133 @@
+package tradfritest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	tradfri "github.com/barnybug/go-tradfri"
+	"github.com/dustin/go-coap"
+)
+
+const loopbackIdentity = "loopback"
+
+// loopback is a tradfri.Transport that passes requests directly to a Server.
+type loopback struct {
+	server *Server
+
+	mu        sync.Mutex
+	msgID     uint16
+	observers map[string]chan coap.Message
+	closed    bool
+}
+
+// Loopback returns a Transport connected directly to the server's model,
+// bypassing DTLS and UDP. Use it as a Client's Dial:
+//
+//	client.Dial = func() (tradfri.Transport, error) { return server.Loopback(), nil }
+func (s *Server) Loopback() tradfri.Transport {
+	s.AddIdent(loopbackIdentity, "")
+	return &loopback{
+		server:    s,
+		observers: map[string]chan coap.Message{},
+	}
+}
+
+func (l *loopback) identity() string {
+	return loopbackIdentity
+}
+
+func (l *loopback) key() string {
+	return ""
+}
+
+func (l *loopback) send(msg coap.Message) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.observers[string(msg.Token)]
+	if !ok || l.closed {
+		return
+	}
+	select {
+	case ch <- encode(msg):
+	default:
+	}
+}
+
+func (l *loopback) request(method coap.COAPCode, path string, payload []byte) coap.Message {
+	l.mu.Lock()
+	l.msgID++
+	req := coap.Message{
+		Type:      coap.Confirmable,
+		Code:      method,
+		MessageID: l.msgID,
+		Token:     make([]byte, 4),
+		Payload:   payload,
+	}
+	l.mu.Unlock()
+	rand.Read(req.Token)
+	req.SetPathString(path)
+	return encode(req)
+}
+
+// encode round-trips msg through its wire format, so options have the types
+// they would after transmission.
+func encode(msg coap.Message) coap.Message {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	msg, err = coap.ParseMessage(data)
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
+
+func (l *loopback) Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return coap.Message{}, err
+	}
+	resp := l.server.handle(l, l.request(method, path, payload))
+	return encode(*resp), nil
+}
+
+func (l *loopback) ObserveContext(ctx context.Context, path string) (<-chan coap.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	req := l.request(coap.GET, path, nil)
+	req.SetOption(coap.Observe, 0)
+	req = encode(req)
+	ch := make(chan coap.Message, 16)
+	l.mu.Lock()
+	l.observers[string(req.Token)] = ch
+	l.mu.Unlock()
+
+	resp := encode(*l.server.handle(l, req))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if resp.Option(coap.Observe) == nil {
+		delete(l.observers, string(req.Token))
+		return nil, errors.New("Resource not observable: " + path)
+	}
+	ch <- resp
+	return ch, nil
+}
+
+func (l *loopback) Ping() error {
+	return nil
+}
+
+func (l *loopback) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		for _, ch := range l.observers {
+			close(ch)
+		}
+	}
+	return nil
+}
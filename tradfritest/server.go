@@ -0,0 +1,742 @@
+// Package tradfritest provides an in-process fake Tradfri gateway for
+// integration tests.
+//
+// The Server speaks CoAP over DTLS-PSK on a local UDP port and emulates the
+// device (/15001), group (/15004), mood (/15005), notification (/15006),
+// smart task (/15010) and gateway (/15011) endpoints over an in-memory model,
+// including PSK issuance on /15011/9063 and observation.
+// Loopback connects a Client to the same model without DTLS.
+//
+// The dtls package keeps a single keystore per process, shared by clients and
+// servers, so every handshake succeeds. The Server instead checks that
+// requests come from a known identity, and that Client_identity handshook
+// with Key before issuing a PSK.
+package tradfritest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-coap"
+	"github.com/eriklupander/dtls"
+)
+
+const preauthIdentity = "Client_identity"
+
+// First ids allocated to new groups and moods.
+const (
+	groupIDBase     = 131072
+	moodIDBase      = 196608
+	smartTaskIDBase = 327680
+)
+
+const (
+	pathDevices       = "15001"
+	pathGroups        = "15004"
+	pathMoods         = "15005"
+	pathSmartTasks    = "15010"
+	pathNotifications = "15006"
+	pathIdent         = "15011/9063"
+	pathGatewayInfo   = "15011/15012"
+	pathReboot        = "15011/9030"
+	pathFactoryReset  = "15011/9031"
+	pathGatewayUpdate = "15011/9034"
+	attrName          = "9001"
+	attrID            = "9003"
+	attrCreatedAt     = "9002"
+	attrPower         = "5850"
+	attrDim           = "5851"
+	attrLightControl  = "3311"
+	attrAccessoryLink = "9018"
+	attrActiveMood    = "9039"
+	attrTaskType      = "9040"
+	attrLightSettings = "15013"
+	attrLinkedItems   = "15002"
+)
+
+// Server is a fake Tradfri gateway.
+type Server struct {
+	// Key is the security code accepted from Client_identity when issuing a
+	// PSK.
+	Key string
+
+	listener *dtls.Listener
+	addr     string
+
+	mu        sync.Mutex
+	resources map[string]map[string]interface{}
+	idents    map[string]string
+	peers     map[string]*dtls.Peer
+	observers map[string][]*observer
+	// notifications is the /15006 list, which unlike other resources is
+	// not an object.
+	notifications []interface{}
+	msgID         uint16
+	closed        bool
+}
+
+type observer struct {
+	session session
+	token   []byte
+	seq     uint32
+}
+
+// session is a client connection to the server.
+type session interface {
+	identity() string
+	// key returns the PSK the session handshook with.
+	key() string
+	send(msg coap.Message)
+}
+
+type dtlsSession struct {
+	peer *dtls.Peer
+}
+
+func (d dtlsSession) identity() string {
+	return d.peer.SessionIdentity()
+}
+
+func (d dtlsSession) key() string {
+	return string(dtls.GetPskFromKeystore(d.identity(), d.peer.RemoteAddr()))
+}
+
+func (d dtlsSession) send(msg coap.Message) {
+	data, err := msg.MarshalBinary()
+	if err == nil {
+		d.peer.Write(data)
+	}
+}
+
+// NewServer starts a fake gateway on a free localhost port, accepting key as
+// its security code.
+func NewServer(key string) (*Server, error) {
+	addr, err := freeAddr()
+	if err != nil {
+		return nil, err
+	}
+	listener, err := dtls.NewUdpListener(addr, time.Second*900)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Key:           key,
+		listener:      listener,
+		addr:          addr,
+		resources:     map[string]map[string]interface{}{},
+		idents:        map[string]string{},
+		peers:         map[string]*dtls.Peer{},
+		observers:     map[string][]*observer{},
+		notifications: []interface{}{},
+		msgID:         uint16(rand.Intn(1 << 16)),
+	}
+	s.resources[pathGatewayInfo] = map[string]interface{}{
+		"9081": "000000000000000a",
+		"9023": "pool.ntp.org",
+		"9029": "1.0.0",
+		"9059": float64(time.Now().Unix()),
+		"9060": time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"),
+		"9062": float64(0),
+	}
+	receivers.serve(addr, true)
+	go s.serve()
+	return s, nil
+}
+
+// freeAddr finds an unused localhost UDP port.
+func freeAddr() (string, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String(), nil
+}
+
+// Addr returns the host:port of the server, suitable for Client.Gateway.
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	receivers.settle(s.addr)
+	err := s.listener.Shutdown()
+	receivers.serve(s.addr, false)
+	return err
+}
+
+// DropSessions forgets all DTLS sessions and observations, as a gateway
+// reboot would. Clients must handshake again.
+func (s *Server) DropSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for addr, peer := range s.peers {
+		s.listener.RemovePeer(peer, dtls.AlertDesc_Noop)
+		delete(s.peers, addr)
+	}
+	s.observers = map[string][]*observer{}
+}
+
+// AddIdent registers an identity and PSK as if previously issued, so clients
+// can connect without the security code.
+func (s *Server) AddIdent(ident, psk string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idents[ident] = psk
+}
+
+// SetDevice stores v, which must encode to a JSON object, as device id.
+func (s *Server) SetDevice(id int, v interface{}) error {
+	return s.SetResource(fmt.Sprintf("%s/%d", pathDevices, id), v)
+}
+
+// SetGroup stores v, which must encode to a JSON object, as group id.
+func (s *Server) SetGroup(id int, v interface{}) error {
+	return s.SetResource(fmt.Sprintf("%s/%d", pathGroups, id), v)
+}
+
+// SetResource stores v, which must encode to a JSON object, at path (without
+// a leading slash) and notifies observers.
+func (s *Server) SetResource(path string, v interface{}) error {
+	obj, err := toObject(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[path] = obj
+	s.notify(path)
+	return nil
+}
+
+// AddNotification appends a notification, such as a *tradfri.Notification,
+// to /15006 and notifies its observers.
+func (s *Server) AddNotification(v interface{}) error {
+	obj, err := toObject(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifications = append(s.notifications, obj)
+	s.notify(pathNotifications)
+	return nil
+}
+
+// Device decodes device id into v.
+func (s *Server) Device(id int, v interface{}) error {
+	return s.Resource(fmt.Sprintf("%s/%d", pathDevices, id), v)
+}
+
+// Group decodes group id into v.
+func (s *Server) Group(id int, v interface{}) error {
+	return s.Resource(fmt.Sprintf("%s/%d", pathGroups, id), v)
+}
+
+// Resource decodes the resource at path into v.
+func (s *Server) Resource(path string, v interface{}) error {
+	s.mu.Lock()
+	obj, ok := s.resources[path]
+	var data []byte
+	if ok {
+		data, _ = json.Marshal(obj)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("tradfritest: no resource %s", path)
+	}
+	return json.Unmarshal(data, v)
+}
+
+func toObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	err = json.Unmarshal(data, &obj)
+	return obj, err
+}
+
+func (s *Server) serve() {
+	for {
+		data, peer := s.listener.Read()
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		s.peers[peer.RemoteAddr()] = peer
+		s.mu.Unlock()
+
+		req, err := coap.ParseMessage(data)
+		if err != nil {
+			continue
+		}
+		sess := dtlsSession{peer}
+		resp := s.handle(sess, req)
+		if resp != nil {
+			// notify also writes to peers, under s.mu
+			s.mu.Lock()
+			sess.send(*resp)
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Server) handle(sess session, req coap.Message) *coap.Message {
+	switch req.Type {
+	case coap.Acknowledgement, coap.Reset:
+		return nil
+	}
+	resp := &coap.Message{
+		Type:      coap.Acknowledgement,
+		MessageID: req.MessageID,
+		Token:     req.Token,
+	}
+	if req.Code == 0 {
+		// CoAP ping
+		resp.Type = coap.Reset
+		return resp
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Type == coap.NonConfirmable {
+		resp.Type = coap.NonConfirmable
+		resp.MessageID = s.nextMessageID()
+	}
+
+	ident := sess.identity()
+	path := req.PathString()
+	if ident == preauthIdentity {
+		if req.Code == coap.POST && path == pathIdent && sess.key() == s.Key {
+			s.issuePSK(req, resp)
+		} else {
+			resp.Code = coap.Unauthorized
+		}
+		return resp
+	}
+	if _, ok := s.idents[ident]; !ok {
+		resp.Code = coap.Unauthorized
+		return resp
+	}
+
+	switch req.Code {
+	case coap.GET:
+		s.get(sess, req, resp)
+	case coap.PUT:
+		s.put(req, resp)
+	case coap.POST:
+		s.post(req, resp)
+	case coap.DELETE:
+		s.delete(req, resp)
+	default:
+		resp.Code = coap.MethodNotAllowed
+	}
+	return resp
+}
+
+func (s *Server) issuePSK(req coap.Message, resp *coap.Message) {
+	var body struct {
+		Ident string `json:"9090"`
+	}
+	if err := json.Unmarshal(req.Payload, &body); err != nil || body.Ident == "" {
+		resp.Code = coap.BadRequest
+		return
+	}
+	psk := randomString(16)
+	s.idents[body.Ident] = psk
+	resp.Code = coap.Created
+	resp.Payload, _ = json.Marshal(map[string]string{
+		"9091": psk,
+		"9029": "1.0.0",
+	})
+}
+
+func (s *Server) get(sess session, req coap.Message, resp *coap.Message) {
+	path := req.PathString()
+	payload, ok := s.render(path)
+	if !ok {
+		resp.Code = coap.NotFound
+		return
+	}
+	resp.Code = coap.Content
+	resp.Payload = payload
+	resp.SetOption(coap.ContentFormat, coap.AppJSON)
+
+	if obs, ok := req.Option(coap.Observe).(uint32); ok {
+		if obs == 0 {
+			o := &observer{session: sess, token: req.Token}
+			s.observers[path] = append(s.observers[path], o)
+			resp.SetOption(coap.Observe, int(o.seq))
+		} else {
+			s.unobserve(path, req.Token)
+		}
+	}
+}
+
+// render returns the JSON for path: a stored object, or the IDs of its
+// children for a collection.
+func (s *Server) render(path string) ([]byte, bool) {
+	if path == pathNotifications {
+		data, _ := json.Marshal(s.notifications)
+		return data, true
+	}
+	if obj, ok := s.resources[path]; ok {
+		data, _ := json.Marshal(obj)
+		return data, true
+	}
+	ids := s.children(path)
+	if ids == nil {
+		return nil, false
+	}
+	data, _ := json.Marshal(ids)
+	return data, true
+}
+
+// children returns the numeric IDs of resources directly below path, or nil
+// if path is not a known collection.
+func (s *Server) children(path string) []int {
+	ids := []int{}
+	prefix := path + "/"
+	for p := range s.resources {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(p, prefix)
+		if strings.Contains(rest, "/") {
+			continue
+		}
+		if id, err := strconv.Atoi(rest); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 && !s.isCollection(path) {
+		return nil
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// isCollection reports whether path is a collection that exists even when
+// empty: devices, groups, smart tasks and the moods of each group.
+func (s *Server) isCollection(path string) bool {
+	if path == pathDevices || path == pathGroups || path == pathSmartTasks {
+		return true
+	}
+	if strings.HasPrefix(path, pathMoods+"/") {
+		_, ok := s.resources[pathGroups+strings.TrimPrefix(path, pathMoods)]
+		return ok
+	}
+	return false
+}
+
+func (s *Server) unobserve(path string, token []byte) {
+	obs := s.observers[path]
+	for i, o := range obs {
+		if string(o.token) == string(token) {
+			s.observers[path] = append(obs[:i], obs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Server) put(req coap.Message, resp *coap.Message) {
+	path := req.PathString()
+	obj, ok := s.resources[path]
+	if !ok {
+		resp.Code = coap.NotFound
+		return
+	}
+	var change map[string]interface{}
+	if err := json.Unmarshal(req.Payload, &change); err != nil {
+		resp.Code = coap.BadRequest
+		return
+	}
+	merge(obj, change)
+	s.notify(path)
+	if strings.HasPrefix(path, pathGroups+"/") {
+		s.applyToMembers(obj, change)
+		if mood, ok := change[attrActiveMood]; ok {
+			s.applyMood(pathMoods+strings.TrimPrefix(path, pathGroups), mood)
+		}
+	}
+	resp.Code = coap.Changed
+}
+
+// applyToMembers copies light state set on a group to its member devices.
+func (s *Server) applyToMembers(group, change map[string]interface{}) {
+	light := map[string]interface{}{}
+	for k, v := range change {
+		if k != attrName && k != attrAccessoryLink {
+			light[k] = v
+		}
+	}
+	if len(light) == 0 {
+		return
+	}
+	for _, id := range memberIDs(group) {
+		path := fmt.Sprintf("%s/%d", pathDevices, id)
+		device, ok := s.resources[path]
+		if !ok {
+			continue
+		}
+		lcs, ok := device[attrLightControl].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, lc := range lcs {
+			if lc, ok := lc.(map[string]interface{}); ok {
+				merge(lc, light)
+			}
+		}
+		s.notify(path)
+	}
+}
+
+// applyMood sets the light settings stored in a mood on its devices.
+func (s *Server) applyMood(moods string, id interface{}) {
+	mood, ok := s.resources[fmt.Sprintf("%s/%v", moods, id)]
+	if !ok {
+		return
+	}
+	settings, _ := mood[attrLightSettings].([]interface{})
+	for _, setting := range settings {
+		setting, ok := setting.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := fmt.Sprintf("%s/%v", pathDevices, setting[attrID])
+		device, ok := s.resources[path]
+		if !ok {
+			continue
+		}
+		lcs, _ := device[attrLightControl].([]interface{})
+		for _, lc := range lcs {
+			if lc, ok := lc.(map[string]interface{}); ok {
+				for k, v := range setting {
+					if k != attrID {
+						lc[k] = v
+					}
+				}
+			}
+		}
+		s.notify(path)
+	}
+}
+
+func memberIDs(group map[string]interface{}) []int {
+	link, _ := group[attrAccessoryLink].(map[string]interface{})
+	items, _ := link[attrLinkedItems].(map[string]interface{})
+	raw, _ := items[attrID].([]interface{})
+	var ids []int
+	for _, v := range raw {
+		if f, ok := v.(float64); ok {
+			ids = append(ids, int(f))
+		}
+	}
+	return ids
+}
+
+func (s *Server) post(req coap.Message, resp *coap.Message) {
+	path := req.PathString()
+	if strings.HasPrefix(path, pathMoods+"/") && s.isCollection(path) {
+		s.create(path, moodIDBase, req, resp, initMood)
+		return
+	}
+	switch path {
+	case pathGroups:
+		s.create(path, groupIDBase, req, resp, initGroup)
+	case pathSmartTasks:
+		s.create(path, smartTaskIDBase, req, resp, initSmartTask)
+	case pathReboot, pathGatewayUpdate:
+		resp.Code = coap.Changed
+		go s.DropSessions()
+	case pathFactoryReset:
+		resp.Code = coap.Changed
+		for path := range s.resources {
+			if path != pathGatewayInfo {
+				delete(s.resources, path)
+			}
+		}
+		s.notifications = []interface{}{}
+		s.idents = map[string]string{}
+		go s.DropSessions()
+	default:
+		resp.Code = coap.MethodNotAllowed
+	}
+}
+
+// merge copies src into dst, recursing into objects and merging arrays of
+// objects element by element.
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if d, ok := dst[k].(map[string]interface{}); ok {
+				merge(d, v)
+				continue
+			}
+		case []interface{}:
+			if d, ok := dst[k].([]interface{}); ok && len(d) == len(v) {
+				for i := range v {
+					dm, dok := d[i].(map[string]interface{})
+					sm, sok := v[i].(map[string]interface{})
+					if dok && sok {
+						merge(dm, sm)
+					} else {
+						d[i] = v[i]
+					}
+				}
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// create adds an object to collection with the next free id from base,
+// replying with the id. init validates the request body and fills in default
+// attributes, returning false for a bad request.
+func (s *Server) create(collection string, base int, req coap.Message, resp *coap.Message, init func(obj map[string]interface{}) bool) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(req.Payload, &obj); err != nil || obj == nil || !init(obj) {
+		resp.Code = coap.BadRequest
+		return
+	}
+	id := base
+	for _, existing := range s.children(collection) {
+		if existing >= id {
+			id = existing + 1
+		}
+	}
+	obj[attrID] = id
+	obj[attrCreatedAt] = time.Now().Unix()
+	// round trip so numbers are float64, as for other resources
+	obj, _ = toObject(obj)
+	s.resources[fmt.Sprintf("%s/%d", collection, id)] = obj
+	s.notify(collection)
+	resp.Code = coap.Created
+	resp.Payload, _ = json.Marshal(map[string]int{attrID: id})
+}
+
+func initGroup(obj map[string]interface{}) bool {
+	obj[attrPower] = 0
+	obj[attrDim] = 0
+	link, _ := obj[attrAccessoryLink].(map[string]interface{})
+	items, _ := link[attrLinkedItems].(map[string]interface{})
+	_, ok := items[attrID].([]interface{})
+	return obj[attrName] != nil && ok
+}
+
+func initMood(obj map[string]interface{}) bool {
+	return obj[attrName] != nil
+}
+
+func initSmartTask(obj map[string]interface{}) bool {
+	if _, ok := obj[attrPower]; !ok {
+		obj[attrPower] = 1
+	}
+	return obj[attrTaskType] != nil
+}
+
+// delete removes a device, group, mood or smart task. Removed devices are also unlinked from
+// their groups.
+func (s *Server) delete(req coap.Message, resp *coap.Message) {
+	path := req.PathString()
+	if !strings.HasPrefix(path, pathDevices+"/") && !strings.HasPrefix(path, pathGroups+"/") &&
+		!strings.HasPrefix(path, pathMoods+"/") && !strings.HasPrefix(path, pathSmartTasks+"/") {
+		resp.Code = coap.MethodNotAllowed
+		return
+	}
+	obj, ok := s.resources[path]
+	if !ok {
+		resp.Code = coap.NotFound
+		return
+	}
+	delete(s.resources, path)
+	s.notify(path)
+	if strings.HasPrefix(path, pathDevices+"/") {
+		s.unlinkDevice(obj[attrID])
+	}
+	resp.Code = coap.Deleted
+}
+
+// unlinkDevice removes a device id from the groups linking to it.
+func (s *Server) unlinkDevice(id interface{}) {
+	for path, group := range s.resources {
+		if !strings.HasPrefix(path, pathGroups+"/") {
+			continue
+		}
+		link, _ := group[attrAccessoryLink].(map[string]interface{})
+		items, _ := link[attrLinkedItems].(map[string]interface{})
+		ids, _ := items[attrID].([]interface{})
+		var kept []interface{}
+		for _, v := range ids {
+			if v != id {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) != len(ids) {
+			items[attrID] = kept
+			s.notify(path)
+		}
+	}
+}
+
+// notify sends the current state of path to its observers. It must be called
+// with s.mu held.
+func (s *Server) notify(path string) {
+	obs := s.observers[path]
+	if len(obs) == 0 {
+		return
+	}
+	payload, ok := s.render(path)
+	for _, o := range obs {
+		o.seq++
+		msg := coap.Message{
+			Type:      coap.Confirmable,
+			Code:      coap.Content,
+			MessageID: s.nextMessageID(),
+			Token:     o.token,
+			Payload:   payload,
+		}
+		if !ok {
+			msg.Code = coap.NotFound
+			msg.Payload = nil
+		}
+		msg.SetOption(coap.Observe, int(o.seq))
+		o.session.send(msg)
+	}
+	if !ok {
+		delete(s.observers, path)
+	}
+}
+
+func (s *Server) nextMessageID() uint16 {
+	s.msgID++
+	return s.msgID
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
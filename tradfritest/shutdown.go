@@ -0,0 +1,117 @@
+package tradfritest
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/eriklupander/dtls"
+)
+
+// A dtls listener reads its shutdown flag, unsynchronised, at the start of
+// each receiver goroutine, and a receiver starts the next one as soon as a
+// packet arrives. The race detector therefore reports the Shutdown of any
+// listener that has received something, in both the Server and the clients
+// under test. Each receiver logs straight after reading the flag, which the
+// log hook below uses to order those reads before Shutdown: Server.Close
+// waits for its own receivers first, and a client receiver is held back
+// until the one it started has read the flag, so the packet it delivers
+// comes after that read.
+func init() {
+	dtls.SetLogFunc(receivers.log)
+	dtls.SetLogLevel(dtls.LogLevelDebug)
+}
+
+var receiverLog = regexp.MustCompile(`\[udp\]\[(.*?)\] (waiting for packet|receiver shutting down|received from (?:unknown )?peer|failed to read packet)`)
+
+var receivers = newReceiverCounts()
+
+// receiverCounts counts, per listener address, the receivers started by
+// another and the receivers that have read the shutdown flag.
+type receiverCounts struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	started map[string]int
+	waited  map[string]int
+	servers map[string]bool
+}
+
+func newReceiverCounts() *receiverCounts {
+	r := &receiverCounts{
+		started: map[string]int{},
+		waited:  map[string]int{},
+		servers: map[string]bool{},
+	}
+	r.cond = sync.NewCond(&r.mu)
+	return r
+}
+
+func (r *receiverCounts) log(ts time.Time, level string, peer string, msg string) {
+	m := receiverLog.FindStringSubmatch(msg)
+	if m == nil {
+		return
+	}
+	addr := m[1]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch m[2] {
+	case "waiting for packet":
+		r.waited[addr]++
+		r.cond.Broadcast()
+	case "receiver shutting down", "failed to read packet":
+		// the listener is done
+		delete(r.started, addr)
+		delete(r.waited, addr)
+		r.cond.Broadcast()
+	default:
+		if _, ok := r.waited[addr]; !ok {
+			// shut down meanwhile
+			return
+		}
+		r.started[addr]++
+		if !r.servers[addr] {
+			// holding back a server receiver would reorder the
+			// handshake flights of its peers
+			r.wait(addr)
+		}
+	}
+}
+
+// settle waits until every receiver of the listener at addr has read the
+// shutdown flag.
+func (r *receiverCounts) settle(addr string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wait(addr)
+}
+
+// wait must be called with r.mu held. It gives up after a second rather
+// than hang a test on a missed log line.
+func (r *receiverCounts) wait(addr string) {
+	timeout := time.AfterFunc(time.Second, func() {
+		r.mu.Lock()
+		r.cond.Broadcast()
+		r.mu.Unlock()
+	})
+	defer timeout.Stop()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		waited, ok := r.waited[addr]
+		// the first receiver is started by the listener itself
+		if !ok || waited > r.started[addr] {
+			return
+		}
+		r.cond.Wait()
+	}
+}
+
+// serve marks addr as the address of a Server listener, or no longer.
+func (r *receiverCounts) serve(addr string, serving bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if serving {
+		r.servers[addr] = true
+	} else {
+		delete(r.servers, addr)
+	}
+}
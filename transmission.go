@@ -0,0 +1,60 @@
+package tradfri
+
+import (
+	"math/rand"
+	"time"
+)
+
+// TransmissionParams control the retransmission of confirmable messages, as
+// described in RFC 7252 section 4.8.
+type TransmissionParams struct {
+	// AckTimeout is the initial time to wait for an acknowledgement.
+	AckTimeout time.Duration
+	// AckRandomFactor randomises the initial timeout to between AckTimeout
+	// and AckTimeout*AckRandomFactor.
+	AckRandomFactor float64
+	// MaxRetransmit is the number of retransmissions before giving up.
+	MaxRetransmit int
+}
+
+// DefaultTransmissionParams are the defaults from RFC 7252.
+var DefaultTransmissionParams = TransmissionParams{
+	AckTimeout:      2 * time.Second,
+	AckRandomFactor: 1.5,
+	MaxRetransmit:   4,
+}
+
+// exchangeLifetime is how long a message ID from the peer is remembered for
+// duplicate detection, using the RFC 7252 defaults.
+const exchangeLifetime = 247 * time.Second
+
+// withDefaults returns p with any unset fields taken from
+// DefaultTransmissionParams. The zero value yields the defaults.
+func (p TransmissionParams) withDefaults() TransmissionParams {
+	if p == (TransmissionParams{}) {
+		return DefaultTransmissionParams
+	}
+	if p.AckTimeout <= 0 {
+		p.AckTimeout = DefaultTransmissionParams.AckTimeout
+	}
+	if p.AckRandomFactor < 1 {
+		p.AckRandomFactor = DefaultTransmissionParams.AckRandomFactor
+	}
+	if p.MaxRetransmit < 0 {
+		p.MaxRetransmit = 0
+	}
+	return p
+}
+
+// initialTimeout picks a random timeout for the first transmission.
+func (p TransmissionParams) initialTimeout() time.Duration {
+	spread := float64(p.AckTimeout) * (p.AckRandomFactor - 1)
+	return p.AckTimeout + time.Duration(rand.Float64()*spread)
+}
+
+// MaxTransmitWait is the longest time from the first transmission of a
+// confirmable message until giving up on an acknowledgement.
+func (p TransmissionParams) MaxTransmitWait() time.Duration {
+	factor := float64(int(1)<<uint(p.MaxRetransmit+1) - 1)
+	return time.Duration(float64(p.AckTimeout) * factor * p.AckRandomFactor)
+}
@@ -0,0 +1,31 @@
+package tradfri
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxTransmitWait(t *testing.T) {
+	assert.Equal(t, 93*time.Second, DefaultTransmissionParams.MaxTransmitWait())
+	p := TransmissionParams{AckTimeout: time.Second, AckRandomFactor: 1, MaxRetransmit: 0}
+	assert.Equal(t, time.Second, p.MaxTransmitWait())
+}
+
+func TestInitialTimeout(t *testing.T) {
+	p := DefaultTransmissionParams
+	for i := 0; i < 100; i++ {
+		timeout := p.initialTimeout()
+		assert.True(t, timeout >= 2*time.Second)
+		assert.True(t, timeout <= 3*time.Second)
+	}
+}
+
+func TestTransmissionParamsWithDefaults(t *testing.T) {
+	p := TransmissionParams{MaxRetransmit: 2}.withDefaults()
+	assert.Equal(t, 2*time.Second, p.AckTimeout)
+	assert.Equal(t, 1.5, p.AckRandomFactor)
+	assert.Equal(t, 2, p.MaxRetransmit)
+	assert.Equal(t, DefaultTransmissionParams, TransmissionParams{}.withDefaults())
+}
@@ -0,0 +1,80 @@
+package tradfri
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/dustin/go-coap"
+)
+
+// Transport carries CoAP requests from a Client to a gateway. DtlsClient is
+// the standard Transport; UDPClient and custom implementations allow Clients
+// to be driven by simulators, in-memory fakes or recorders.
+type Transport interface {
+	// Do sends a request for path with the given method and payload, and
+	// returns the response. It should return ErrTimeout if the gateway does
+	// not respond, so the Client can reconnect.
+	Do(ctx context.Context, method coap.COAPCode, path string, payload []byte) (coap.Message, error)
+	// ObserveContext registers an observation of path. The initial response
+	// and every notification are delivered on the returned channel, which is
+	// closed when the Transport is closed.
+	ObserveContext(ctx context.Context, path string) (<-chan coap.Message, error)
+	// Ping checks that the gateway is still responding.
+	Ping() error
+	// Close releases the Transport's resources.
+	Close() error
+}
+
+var (
+	_ Transport = (*DtlsClient)(nil)
+	_ Transport = (*UDPClient)(nil)
+)
+
+// UDPClient is a CoAP client over plain UDP, for use with gateway simulators.
+type UDPClient struct {
+	*endpoint
+	conn *net.UDPConn
+}
+
+// NewUDPClient returns a client for the CoAP server at address (host:port).
+func NewUDPClient(address string) (*UDPClient, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &UDPClient{
+		endpoint: newEndpoint(udpConn{conn}),
+		conn:     conn,
+	}, nil
+}
+
+// Close releases the socket and stops the receiver, which then closes all
+// observation channels.
+func (uc *UDPClient) Close() error {
+	uc.endpoint.close()
+	return uc.conn.Close()
+}
+
+type udpConn struct {
+	*net.UDPConn
+}
+
+func (c udpConn) Read(timeout time.Duration) ([]byte, error) {
+	buf := make([]byte, 2048)
+	c.SetReadDeadline(time.Now().Add(timeout))
+	n, err := c.UDPConn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func (c udpConn) Write(data []byte) error {
+	_, err := c.UDPConn.Write(data)
+	return err
+}
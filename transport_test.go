@@ -0,0 +1,101 @@
+package tradfri
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dustin/go-coap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCoAP(t *testing.T, handler func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message) *UDPClient {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	go coap.Serve(conn, coap.FuncHandler(handler))
+	t.Cleanup(func() { conn.Close() })
+
+	client, err := NewUDPClient(conn.LocalAddr().String())
+	require.NoError(t, err)
+	client.Params = TransmissionParams{
+		AckTimeout:      50 * time.Millisecond,
+		AckRandomFactor: 1,
+		MaxRetransmit:   2,
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func content(m *coap.Message, payload string) *coap.Message {
+	return &coap.Message{
+		Type:      coap.Acknowledgement,
+		Code:      coap.Content,
+		MessageID: m.MessageID,
+		Token:     m.Token,
+		Payload:   []byte(payload),
+	}
+}
+
+func TestUDPClientPiggybacked(t *testing.T) {
+	client := serveCoAP(t, func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message {
+		return content(m, m.PathString())
+	})
+	resp, err := client.Do(context.Background(), coap.GET, "/15001/65536", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "15001/65536", string(resp.Payload))
+}
+
+func TestUDPClientRetransmit(t *testing.T) {
+	var requests int32
+	client := serveCoAP(t, func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// drop the first transmission
+			return nil
+		}
+		return content(m, "ok")
+	})
+	resp, err := client.Do(context.Background(), coap.GET, "/15001", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(resp.Payload))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestUDPClientTimeout(t *testing.T) {
+	var requests int32
+	client := serveCoAP(t, func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message {
+		atomic.AddInt32(&requests, 1)
+		return nil
+	})
+	_, err := client.Do(context.Background(), coap.GET, "/15001", nil)
+	assert.Equal(t, ErrTimeout, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestUDPClientSeparateResponse(t *testing.T) {
+	client := serveCoAP(t, func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message {
+		coap.Transmit(l, a, coap.Message{Type: coap.Acknowledgement, MessageID: m.MessageID})
+		resp := content(m, "later")
+		resp.Type = coap.Confirmable
+		resp.MessageID = m.MessageID + 1000
+		// a duplicate, as if our ACK had been lost, must not be delivered twice
+		coap.Transmit(l, a, *resp)
+		coap.Transmit(l, a, *resp)
+		return nil
+	})
+	resp, err := client.Do(context.Background(), coap.GET, "/15001", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "later", string(resp.Payload))
+}
+
+func TestUDPClientContext(t *testing.T) {
+	client := serveCoAP(t, func(l *net.UDPConn, a *net.UDPAddr, m *coap.Message) *coap.Message {
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := client.Do(ctx, coap.GET, "/15001", nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -2,6 +2,7 @@ package tradfri
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -24,6 +25,86 @@ type LightControl struct {
 	Duration *int    `json:"5712,omitempty"`
 }
 
+// Sensor is a generic IPSO sensor reading, as reported by motion sensors.
+type Sensor struct {
+	Value       *float64 `json:"5700,omitempty"`
+	Units       string   `json:"5701,omitempty"`
+	MinMeasured *float64 `json:"5601,omitempty"`
+	MaxMeasured *float64 `json:"5602,omitempty"`
+	MinRange    *float64 `json:"5603,omitempty"`
+	MaxRange    *float64 `json:"5604,omitempty"`
+	SensorType  string   `json:"5751,omitempty"`
+}
+
+// PlugControl is the state of a control outlet.
+type PlugControl struct {
+	Power *int `json:"5850,omitempty"`
+	Dim   *int `json:"5851,omitempty"`
+}
+
+// BlindControl is the state of a window covering. Position is 0 (open) to
+// 100 (closed); setting Trigger to 0 stops a blind in motion.
+type BlindControl struct {
+	Position *float64 `json:"5536,omitempty"`
+	Trigger  *int     `json:"5523,omitempty"`
+}
+
+// AirPurifierControl is the state of an air purifier. Runtimes and lifetimes
+// are in minutes and AirQuality is PM2.5 in µg/m³.
+type AirPurifierControl struct {
+	FanMode         *int `json:"5900,omitempty"`
+	FilterRuntime   *int `json:"5902,omitempty"`
+	FilterStatus    *int `json:"5903,omitempty"`
+	FilterLifetime  *int `json:"5904,omitempty"`
+	ChildLock       *int `json:"5905,omitempty"`
+	LEDDisable      *int `json:"5906,omitempty"`
+	AirQuality      *int `json:"5907,omitempty"`
+	FanSpeed        *int `json:"5908,omitempty"`
+	MotorRuntime    *int `json:"5909,omitempty"`
+	FilterRemaining *int `json:"5910,omitempty"`
+}
+
+func (a *AirPurifierControl) String() string {
+	s := "Mode: "
+	switch {
+	case a.FanMode == nil:
+		s += "unknown"
+	case *a.FanMode == FanModeOff:
+		s += "off"
+	case *a.FanMode == FanModeAuto:
+		s += "auto"
+	default:
+		s += "manual"
+	}
+	if a.FanSpeed != nil {
+		s += fmt.Sprintf(", Fan speed: %d", *a.FanSpeed)
+	}
+	if a.AirQuality != nil {
+		if *a.AirQuality == AirQualityUnknown {
+			s += ", PM2.5: unknown"
+		} else {
+			s += fmt.Sprintf(", PM2.5: %d", *a.AirQuality)
+		}
+	}
+	if a.FilterRuntime != nil && a.FilterLifetime != nil {
+		s += fmt.Sprintf(", Filter: %d/%d min", *a.FilterRuntime, *a.FilterLifetime)
+	}
+	if a.ChildLock != nil {
+		s += fmt.Sprintf(", Child lock: %s", onOff(*a.ChildLock != 0))
+	}
+	if a.LEDDisable != nil {
+		s += fmt.Sprintf(", LEDs: %s", onOff(*a.LEDDisable == 0))
+	}
+	return s
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
 type DeviceDescription struct {
 	Device struct {
 		Manufacturer          string `json:"0"`
@@ -33,14 +114,54 @@ type DeviceDescription struct {
 		AvailablePowerSources int    `json:"6"`
 		BatteryLevel          int    `json:"9"`
 	} `json:"3"`
-	LightControl      []LightControl `json:"3311"`
-	ApplicationType   int            `json:"5750"`
-	DeviceName        string         `json:"9001"`
-	CreatedAt         int            `json:"9002"`
-	DeviceID          int            `json:"9003"`
-	ReachabilityState int            `json:"9019"`
-	LastSeen          int            `json:"9020"`
-	OTAUpdateState    int            `json:"9054"`
+	LightControl       []LightControl       `json:"3311"`
+	PlugControl        []PlugControl        `json:"3312,omitempty"`
+	Sensor             []Sensor             `json:"3300,omitempty"`
+	BlindControl       []BlindControl       `json:"15015,omitempty"`
+	AirPurifierControl []AirPurifierControl `json:"15025,omitempty"`
+	ApplicationType    ApplicationType      `json:"5750"`
+	DeviceName         string               `json:"9001"`
+	CreatedAt          int                  `json:"9002"`
+	DeviceID           int                  `json:"9003"`
+	ReachabilityState  int                  `json:"9019"`
+	LastSeen           int                  `json:"9020"`
+	OTAUpdateState     OTAState             `json:"9054"`
+}
+
+// OTAState is the firmware update state of a device or the gateway.
+type OTAState int
+
+const (
+	OTAUpToDate    OTAState = 0
+	OTAAvailable   OTAState = 1
+	OTADownloading OTAState = 2
+	OTAReady       OTAState = 3
+	OTAInstalling  OTAState = 4
+)
+
+var OTAStates = map[OTAState]string{
+	OTAUpToDate:    "Up to date",
+	OTAAvailable:   "Update available",
+	OTADownloading: "Downloading",
+	OTAReady:       "Ready to install",
+	OTAInstalling:  "Installing",
+}
+
+func (s OTAState) String() string {
+	if name, ok := OTAStates[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", int(s))
+}
+
+// Pending reports whether an update is waiting to be downloaded or installed.
+func (s OTAState) Pending() bool {
+	return s == OTAAvailable || s == OTAReady
+}
+
+// InProgress reports whether an update is being downloaded or installed.
+func (s OTAState) InProgress() bool {
+	return s == OTADownloading || s == OTAInstalling
 }
 
 var PowerSources = map[int]string{
@@ -53,6 +174,89 @@ var PowerSources = map[int]string{
 	7: "Solar",
 }
 
+// ApplicationType identifies the kind of a device.
+type ApplicationType int
+
+var ApplicationTypes = map[ApplicationType]string{
+	Remote:          "Remote",
+	Remote2:         "Remote",
+	Lamp:            "Light",
+	Plug:            "Plug",
+	MotionSensor:    "Motion sensor",
+	SignalRepeater:  "Signal repeater",
+	Blind:           "Blind",
+	SoundController: "Sound controller",
+	AirPurifier:     "Air purifier",
+}
+
+func (t ApplicationType) String() string {
+	if s, ok := ApplicationTypes[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown (%d)", int(t))
+}
+
+// Capabilities describes the controls a light supports.
+type Capabilities struct {
+	Dimmable      bool
+	WhiteSpectrum bool
+	ColorXY       bool
+	HueSat        bool
+}
+
+func (c Capabilities) String() string {
+	var caps []string
+	for _, f := range []struct {
+		ok   bool
+		name string
+	}{
+		{c.Dimmable, "dimmable"},
+		{c.WhiteSpectrum, "white spectrum"},
+		{c.ColorXY, "xy"},
+		{c.HueSat, "hue/sat"},
+	} {
+		if f.ok {
+			caps = append(caps, f.name)
+		}
+	}
+	return strings.Join(caps, ", ")
+}
+
+// IsLight reports whether the device is a light.
+func (d *DeviceDescription) IsLight() bool {
+	return d.ApplicationType == Lamp
+}
+
+// IsBlind reports whether the device is a blind.
+func (d *DeviceDescription) IsBlind() bool {
+	return d.ApplicationType == Blind
+}
+
+// HasBattery reports whether the device is battery powered and reports its
+// battery level.
+func (d *DeviceDescription) HasBattery() bool {
+	switch d.ApplicationType {
+	case Remote, Remote2, MotionSensor, Blind, SoundController:
+		return true
+	}
+	return false
+}
+
+// Capabilities returns the controls supported by a light, or none for other
+// devices.
+func (d *DeviceDescription) Capabilities() Capabilities {
+	var caps Capabilities
+	if !d.IsLight() || len(d.LightControl) == 0 {
+		return caps
+	}
+	lc := d.LightControl[0]
+	caps.Dimmable = lc.Dim != nil
+	caps.WhiteSpectrum = lc.Mireds != nil
+	caps.ColorXY = lc.ColorX != nil && lc.ColorY != nil
+	caps.HueSat = lc.ColorHue != nil && lc.ColorSat != nil
+	return caps
+}
+
 func (d *DeviceDescription) AvailablePowerSource() string {
 	if s, ok := PowerSources[d.Device.AvailablePowerSources]; ok {
 		return s
@@ -62,81 +266,400 @@ func (d *DeviceDescription) AvailablePowerSource() string {
 }
 
 func (d *DeviceDescription) String() string {
-	s := fmt.Sprintf("ID: %d Name: %q\nType: %d Model: %q\n", d.DeviceID, d.DeviceName, d.ApplicationType, d.Device.ModelNumber)
-	s += fmt.Sprintf("Firmware: %s Manufacturer: %q\n", d.Device.FirmwareVersion, d.Device.Manufacturer)
+	s := fmt.Sprintf("ID: %d Name: %q\nType: %s Model: %q\n", d.DeviceID, d.DeviceName, d.ApplicationType, d.Device.ModelNumber)
+	s += fmt.Sprintf("Firmware: %s", d.Device.FirmwareVersion)
+	if d.OTAUpdateState != OTAUpToDate {
+		s += fmt.Sprintf(" (%s)", d.OTAUpdateState)
+	}
+	s += fmt.Sprintf(" Manufacturer: %q\n", d.Device.Manufacturer)
 	s += fmt.Sprintf("Power: %s", d.AvailablePowerSource())
-	if d.ApplicationType == Remote || d.ApplicationType == Remote2 {
+	if d.HasBattery() {
 		s += fmt.Sprintf(" Level: %v%%", d.Device.BatteryLevel)
 	}
 	s += "\n"
 	lastSeen := time.Unix(int64(d.LastSeen), 0)
 	s += fmt.Sprintf("Last seen: %s\n", lastSeen.Format(time.RFC1123))
-	if d.ApplicationType == Lamp {
+	if d.IsLight() {
+		s += fmt.Sprintf("Capabilities: %s\n", d.Capabilities())
 		for count, entry := range d.LightControl {
 			power := "off"
-			if *entry.Power != 0 {
+			if entry.Power != nil && *entry.Power != 0 {
 				power = "on"
 			}
-			pc := DimToPercentage(*entry.Dim)
-			s += fmt.Sprintf("Light Control Set %d, Power: %s, Dim: %d%%\n",
-				count, power, pc)
-			s += "Color: "
+			s += fmt.Sprintf("Light Control Set %d, Power: %s", count, power)
+			if entry.Dim != nil {
+				s += fmt.Sprintf(", Dim: %d%%", DimToPercentage(*entry.Dim))
+			}
+			s += "\nColor: "
 			if entry.Mireds != nil {
 				s += fmt.Sprintf("%dK ", MiredToKelvin(*entry.Mireds))
 			}
 			if entry.Color != nil {
 				s += fmt.Sprintf("#%s ", *entry.Color)
 			}
-			if entry.ColorX != nil {
+			if entry.ColorX != nil && entry.ColorY != nil {
 				s += fmt.Sprintf("X:%d/Y:%d ", *entry.ColorX, *entry.ColorY)
 			}
-			if entry.ColorHue != nil {
+			if entry.ColorHue != nil && entry.ColorSat != nil {
 				s += fmt.Sprintf("Hue: %d Sat: %d ", *entry.ColorHue, *entry.ColorSat)
 			}
 			s += "\n"
 		}
 	}
+	for count, entry := range d.Sensor {
+		s += fmt.Sprintf("Sensor %d", count)
+		if entry.SensorType != "" {
+			s += fmt.Sprintf(", Type: %s", entry.SensorType)
+		}
+		if entry.Value != nil {
+			s += fmt.Sprintf(", Value: %g%s", *entry.Value, entry.Units)
+		}
+		s += "\n"
+	}
+	if d.ApplicationType == Plug {
+		for count, entry := range d.PlugControl {
+			power := "off"
+			if entry.Power != nil && *entry.Power != 0 {
+				power = "on"
+			}
+			s += fmt.Sprintf("Plug Control Set %d, Power: %s\n", count, power)
+		}
+	}
+	if d.ApplicationType == AirPurifier {
+		for count, entry := range d.AirPurifierControl {
+			s += fmt.Sprintf("Air Purifier Control Set %d, %s\n", count, &entry)
+		}
+	}
+	if d.IsBlind() {
+		for count, entry := range d.BlindControl {
+			s += fmt.Sprintf("Blind Control Set %d", count)
+			if entry.Position != nil {
+				s += fmt.Sprintf(", Position: %.0f%%", *entry.Position)
+			}
+			s += "\n"
+		}
+	}
 	return s
 }
 
-func (d *DeviceDescription) SupportsMired() bool {
-	return d.LightControl[0].Mireds != nil
+type DeviceSet struct {
+	LightControl []LightControl `json:"3311"`
 }
 
-func (d *DeviceDescription) SupportsColorXY() bool {
-	return d.LightControl[0].ColorX != nil
+type NameSet struct {
+	Name string `json:"9001"`
 }
 
-func (d *DeviceDescription) SupportsHueSat() bool {
-	return d.LightControl[0].ColorHue != nil
+type PlugSet struct {
+	PlugControl []PlugControl `json:"3312"`
 }
 
-type DeviceSet struct {
-	LightControl []LightControl `json:"3311"`
+type BlindSet struct {
+	BlindControl []BlindControl `json:"15015"`
+}
+
+type AirPurifierSet struct {
+	AirPurifierControl []AirPurifierControl `json:"15025"`
 }
 
 type GroupDescription struct {
-	Power         int    `json:"5850"`
-	Dim           int    `json:"5851"`
-	GroupName     string `json:"9001"`
-	CreatedAt     int    `json:"9002"`
-	GroupID       int    `json:"9003"`
-	AccessoryLink struct {
-		LinkedItems struct {
-			DeviceIDs []int `json:"9003"`
-		} `json:"15002"`
-	} `json:"9018"`
-	Num9039 int `json:"9039"`
+	Power         int           `json:"5850"`
+	Dim           int           `json:"5851"`
+	GroupName     string        `json:"9001"`
+	CreatedAt     int           `json:"9002"`
+	GroupID       int           `json:"9003"`
+	AccessoryLink AccessoryLink `json:"9018"`
+	ActiveMood    int           `json:"9039"`
+}
+
+// AccessoryLink lists the devices in a group.
+type AccessoryLink struct {
+	LinkedItems struct {
+		DeviceIDs []int `json:"9003"`
+	} `json:"15002"`
+}
+
+type GroupCreate struct {
+	GroupName     string        `json:"9001"`
+	AccessoryLink AccessoryLink `json:"9018"`
+}
+
+type GroupMembersSet struct {
+	AccessoryLink AccessoryLink `json:"9018"`
+}
+
+type GroupCreated struct {
+	GroupID int `json:"9003"`
 }
 
 func (g *GroupDescription) String() string {
 	createdAt := time.Unix(int64(g.CreatedAt), 0)
 	s := fmt.Sprintf("ID: %d Name: %q Created: %s\n", g.GroupID, g.GroupName, createdAt.Format(time.RFC1123))
-	s += fmt.Sprintf("Power: %d Dim: %d\n", g.Power, g.Dim)
+	s += fmt.Sprintf("Power: %d Dim: %d", g.Power, g.Dim)
+	if g.ActiveMood != 0 {
+		s += fmt.Sprintf(" Mood: %d", g.ActiveMood)
+	}
+	s += "\n"
 	s += fmt.Sprintf("Linked devices: %v\n", g.AccessoryLink.LinkedItems.DeviceIDs)
 	return s
 }
 
+// Mood is a scene stored on the gateway for a group.
+type Mood struct {
+	Name          string         `json:"9001"`
+	CreatedAt     int            `json:"9002"`
+	MoodID        int            `json:"9003"`
+	Index         int            `json:"9057"`
+	Predefined    int            `json:"9068"`
+	LightSettings []LightSetting `json:"15013"`
+}
+
+// LightSetting is the state a mood or smart task sets on one device.
+type LightSetting struct {
+	DeviceID int `json:"9003"`
+	LightControl
+}
+
+func (m *Mood) String() string {
+	s := fmt.Sprintf("ID: %d Name: %q", m.MoodID, m.Name)
+	if m.Predefined != 0 {
+		s += " (predefined)"
+	}
+	s += "\n"
+	for _, ls := range m.LightSettings {
+		s += fmt.Sprintf("Device: %d", ls.DeviceID)
+		if ls.Power != nil {
+			s += fmt.Sprintf(" Power: %s", onOff(*ls.Power != 0))
+		}
+		if ls.Dim != nil {
+			s += fmt.Sprintf(" Dim: %d%%", DimToPercentage(*ls.Dim))
+		}
+		if ls.Mireds != nil {
+			s += fmt.Sprintf(" Color: %dK", MiredToKelvin(*ls.Mireds))
+		} else if ls.Color != nil {
+			s += fmt.Sprintf(" Color: #%s", *ls.Color)
+		}
+		s += "\n"
+	}
+	return s
+}
+
+type MoodCreate struct {
+	Name          string         `json:"9001"`
+	LightSettings []LightSetting `json:"15013"`
+}
+
+type MoodCreated struct {
+	MoodID int `json:"9003"`
+}
+
+type MoodActivate struct {
+	Power      int `json:"5850"`
+	ActiveMood int `json:"9039"`
+}
+
+// SmartTaskType is the kind of a smart task.
+type SmartTaskType int
+
+const (
+	TaskNotAtHome SmartTaskType = 1
+	TaskOnOff     SmartTaskType = 2
+	TaskWakeUp    SmartTaskType = 4
+)
+
+var SmartTaskTypes = map[SmartTaskType]string{
+	TaskNotAtHome: "Not at home",
+	TaskOnOff:     "On/off",
+	TaskWakeUp:    "Wake up",
+}
+
+func (t SmartTaskType) String() string {
+	if s, ok := SmartTaskTypes[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown (%d)", int(t))
+}
+
+// Weekdays is a set of days on which a smart task repeats.
+type Weekdays int
+
+const (
+	Monday Weekdays = 1 << iota
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+	Sunday
+
+	WorkingDays = Monday | Tuesday | Wednesday | Thursday | Friday
+	Weekend     = Saturday | Sunday
+	EveryDay    = WorkingDays | Weekend
+)
+
+var weekdayNames = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+func (w Weekdays) String() string {
+	switch w {
+	case EveryDay:
+		return "daily"
+	case WorkingDays:
+		return "weekdays"
+	case Weekend:
+		return "weekend"
+	}
+	var days []string
+	for i, name := range weekdayNames {
+		if w&(1<<uint(i)) != 0 {
+			days = append(days, name)
+		}
+	}
+	return strings.Join(days, ",")
+}
+
+// ParseWeekdays parses a comma separated list of days ("mon,wed"), or one of
+// "daily", "weekdays" or "weekend".
+func ParseWeekdays(s string) (Weekdays, error) {
+	switch s {
+	case "daily":
+		return EveryDay, nil
+	case "weekdays":
+		return WorkingDays, nil
+	case "weekend":
+		return Weekend, nil
+	}
+	var w Weekdays
+	for _, day := range strings.Split(s, ",") {
+		found := false
+		for i, name := range weekdayNames {
+			if strings.EqualFold(strings.TrimSpace(day), name) {
+				w |= 1 << uint(i)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("Unknown day %q", day)
+		}
+	}
+	return w, nil
+}
+
+// TimeInterval is when a smart task runs, in the gateway's time (UTC). End
+// times are only used by "not at home" tasks, and are nil otherwise.
+type TimeInterval struct {
+	StartHour   int  `json:"9046"`
+	StartMinute int  `json:"9047"`
+	EndHour     *int `json:"9048,omitempty"`
+	EndMinute   *int `json:"9049,omitempty"`
+}
+
+func (t TimeInterval) String() string {
+	s := fmt.Sprintf("%02d:%02d", t.StartHour, t.StartMinute)
+	if t.EndHour != nil && t.EndMinute != nil {
+		s += fmt.Sprintf("-%02d:%02d", *t.EndHour, *t.EndMinute)
+	}
+	return s
+}
+
+// StartAction is what a smart task does when it runs.
+type StartAction struct {
+	Power         int            `json:"5850"`
+	LightSettings []LightSetting `json:"15013"`
+}
+
+// SmartTask is a schedule stored on the gateway.
+type SmartTask struct {
+	TaskID      int            `json:"9003,omitempty"`
+	CreatedAt   int            `json:"9002,omitempty"`
+	Enabled     int            `json:"5850"`
+	Type        SmartTaskType  `json:"9040"`
+	RepeatDays  Weekdays       `json:"9041"`
+	StartAction StartAction    `json:"9042"`
+	Intervals   []TimeInterval `json:"9044"`
+}
+
+func (t *SmartTask) String() string {
+	s := fmt.Sprintf("ID: %d Type: %s Enabled: %t Repeat: %s Time:", t.TaskID, t.Type, t.Enabled != 0, t.RepeatDays)
+	for _, interval := range t.Intervals {
+		s += " " + interval.String()
+	}
+	s += "\n"
+	for _, ls := range t.StartAction.LightSettings {
+		s += fmt.Sprintf("Device: %d", ls.DeviceID)
+		if ls.Dim != nil {
+			s += fmt.Sprintf(" Dim: %d%%", DimToPercentage(*ls.Dim))
+		}
+		if ls.Duration != nil {
+			s += fmt.Sprintf(" Transition: %s", time.Duration(*ls.Duration)*100*time.Millisecond)
+		}
+		s += "\n"
+	}
+	return s
+}
+
+type SmartTaskCreated struct {
+	TaskID int `json:"9003"`
+}
+
+type SmartTaskEnable struct {
+	Enabled int `json:"5850"`
+}
+
+// NotificationEvent identifies the kind of a gateway notification.
+type NotificationEvent int
+
+// Known notification events. Other events are reported as unknown. The code
+// of NotificationNewDevice has not been confirmed against a gateway.
+const (
+	NotificationNewFirmware   NotificationEvent = 1001
+	NotificationNewDevice     NotificationEvent = 1002
+	NotificationGatewayReboot NotificationEvent = 1003
+	NotificationLostInternet  NotificationEvent = 5003
+)
+
+var NotificationEvents = map[NotificationEvent]string{
+	NotificationNewFirmware:   "New firmware available",
+	NotificationNewDevice:     "New device",
+	NotificationGatewayReboot: "Gateway rebooted",
+	NotificationLostInternet:  "Lost internet connectivity",
+}
+
+func (e NotificationEvent) String() string {
+	if s, ok := NotificationEvents[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown (%d)", int(e))
+}
+
+// Notification is an event reported by the gateway. Details are "key=value"
+// pairs.
+type Notification struct {
+	Event          NotificationEvent `json:"9015"`
+	Details        []string          `json:"9017"`
+	State          int               `json:"9014"`
+	CreatedAt      int               `json:"9002"`
+	NotificationID int               `json:"9003"`
+}
+
+// Detail returns the value of a detail key, or "" if it is not present.
+func (n *Notification) Detail(key string) string {
+	for _, pair := range n.Details {
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 && kv[0] == key {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+func (n *Notification) String() string {
+	createdAt := time.Unix(int64(n.CreatedAt), 0)
+	s := fmt.Sprintf("%s %s", createdAt.Format(time.RFC1123), n.Event)
+	if len(n.Details) > 0 {
+		s += " " + strings.Join(n.Details, " ")
+	}
+	return s
+}
+
 type PSKRequest struct {
 	Ident string `json:"9090"`
 }
@@ -146,13 +669,90 @@ type PSKResponse struct {
 }
 
 type GatewayInfo struct {
-	ID               string `json:"9081"`
-	NTPServer        string `json:"9023"`
-	FirmwareVersion  string `json:"9029"`
-	CurrentTimestamp int    `json:"9059"`
-	CurrentTime      string `json:"9060"`
+	ID                     string   `json:"9081"`
+	NTPServer              string   `json:"9023"`
+	FirmwareVersion        string   `json:"9029"`
+	CurrentTimestamp       int      `json:"9059"`
+	CurrentTime            string   `json:"9060"`
+	OTAUpdateState         OTAState `json:"9054"`
+	UpdateProgress         int      `json:"9055"`
+	UpdateDetailsURL       string   `json:"9056"`
+	CommissioningMode      int      `json:"9061"`
+	UTCOffset              int      `json:"9062"`
+	OTAType                int      `json:"9066"`
+	FirstSetup             int      `json:"9069"`
+	TimeSource             int      `json:"9071"`
+	HomekitID              string   `json:"9083"`
+	CertificateProvisioned int      `json:"9092"`
+	AlexaPairStatus        int      `json:"9093"`
+	GoogleHomePairStatus   int      `json:"9105"`
+	DaylightSaving
+}
+
+// DaylightSaving holds the gateway's daylight saving rule. The gateway keeps
+// UTC time; Offset minutes are added between the start and end, which are
+// given in UTC.
+type DaylightSaving struct {
+	StartMonth  int `json:"9072"`
+	StartDay    int `json:"9073"`
+	StartHour   int `json:"9074"`
+	StartMinute int `json:"9075"`
+	EndMonth    int `json:"9076"`
+	EndDay      int `json:"9077"`
+	EndHour     int `json:"9078"`
+	EndMinute   int `json:"9079"`
+	Offset      int `json:"9080"`
+}
+
+func (d DaylightSaving) String() string {
+	if d.Offset == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("+%dmin from %02d-%02d %02d:%02d to %02d-%02d %02d:%02d UTC", d.Offset,
+		d.StartMonth, d.StartDay, d.StartHour, d.StartMinute, d.EndMonth, d.EndDay, d.EndHour, d.EndMinute)
+}
+
+// TimezoneSet sets the gateway's UTC offset, in minutes, and daylight saving
+// rule.
+type TimezoneSet struct {
+	UTCOffset int `json:"9062"`
+	DaylightSaving
+}
+
+type OTACheckSet struct {
+	Check int `json:"9032"`
+}
+
+type NTPServerSet struct {
+	NTPServer string `json:"9023"`
+}
+
+type CommissioningSet struct {
+	Timeout int `json:"9061"`
 }
 
 func (g *GatewayInfo) String() string {
 	return fmt.Sprintf("ID: %s\nNTPServer: %s\nFirmware Version: %s\nCurrent Time: %s", g.ID, g.NTPServer, g.FirmwareVersion, g.CurrentTime)
 }
+
+// Details returns all decoded gateway settings, for verbose output.
+func (g *GatewayInfo) Details() string {
+	s := g.String() + "\n"
+	s += fmt.Sprintf("Update State: %s Progress: %d%%\n", g.OTAUpdateState, g.UpdateProgress)
+	if g.UpdateDetailsURL != "" {
+		s += fmt.Sprintf("Update Details: %s\n", g.UpdateDetailsURL)
+	}
+	s += fmt.Sprintf("OTA Type: %d\n", g.OTAType)
+	s += fmt.Sprintf("Commissioning: %s\n", onOff(g.CommissioningMode != 0))
+	if g.FirstSetup != 0 {
+		s += fmt.Sprintf("First Setup: %s\n", time.Unix(int64(g.FirstSetup), 0).UTC().Format(time.RFC1123))
+	}
+	s += fmt.Sprintf("Time Source: %d\n", g.TimeSource)
+	s += fmt.Sprintf("UTC Offset: %dmin\n", g.UTCOffset)
+	s += fmt.Sprintf("Daylight Saving: %s\n", g.DaylightSaving)
+	s += fmt.Sprintf("HomeKit ID: %s\n", g.HomekitID)
+	s += fmt.Sprintf("Certificate Provisioned: %t\n", g.CertificateProvisioned != 0)
+	s += fmt.Sprintf("Alexa Paired: %t\n", g.AlexaPairStatus != 0)
+	s += fmt.Sprintf("Google Home Paired: %t", g.GoogleHomePairStatus != 0)
+	return s
+}
@@ -0,0 +1,125 @@
+package tradfri
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMotionSensor(t *testing.T) {
+	data := `{"3":{"0":"IKEA of Sweden","1":"TRADFRI motion sensor","2":"","3":"2.0.022","6":3,"9":74},` +
+		`"3300":[{"5700":1,"5751":"motion","9003":0}],"5750":4,"9001":"Hallway sensor","9002":1545046543,` +
+		`"9003":65550,"9019":1,"9020":1545049021,"9054":0}`
+	var desc DeviceDescription
+	require.NoError(t, json.Unmarshal([]byte(data), &desc))
+	assert.Equal(t, MotionSensor, desc.ApplicationType)
+	assert.Equal(t, "Motion sensor", desc.ApplicationType.String())
+	assert.True(t, desc.HasBattery())
+	assert.False(t, desc.IsLight())
+	assert.Equal(t, Capabilities{}, desc.Capabilities())
+	require.Len(t, desc.Sensor, 1)
+	assert.Equal(t, 1.0, *desc.Sensor[0].Value)
+	s := desc.String()
+	assert.Contains(t, s, "Type: Motion sensor Model: \"TRADFRI motion sensor\"")
+	assert.Contains(t, s, "Power: Battery Level: 74%")
+	assert.Contains(t, s, "Sensor 0, Type: motion, Value: 1")
+}
+
+func TestApplicationType(t *testing.T) {
+	assert.Equal(t, "Remote", Remote.String())
+	assert.Equal(t, "Signal repeater", SignalRepeater.String())
+	assert.Equal(t, "Unknown (99)", ApplicationType(99).String())
+}
+
+func TestCapabilities(t *testing.T) {
+	data := `{"3311":[{"5706":"f1e0b5","5707":0,"5708":0,"5709":30138,"5710":26909,"5711":370,"5850":1,"5851":203}],` +
+		`"5750":2,"9003":65536}`
+	var desc DeviceDescription
+	require.NoError(t, json.Unmarshal([]byte(data), &desc))
+	assert.True(t, desc.IsLight())
+	caps := desc.Capabilities()
+	assert.Equal(t, Capabilities{Dimmable: true, WhiteSpectrum: true, ColorXY: true, HueSat: true}, caps)
+	assert.Equal(t, "dimmable, white spectrum, xy, hue/sat", caps.String())
+
+	// lights without any light control entries no longer panic
+	desc.LightControl = nil
+	assert.Equal(t, Capabilities{}, desc.Capabilities())
+}
+
+func TestDecodeOnOffLight(t *testing.T) {
+	var desc DeviceDescription
+	require.NoError(t, json.Unmarshal([]byte(`{"3311":[{"5850":1}],"5750":2}`), &desc))
+	assert.Equal(t, Capabilities{}, desc.Capabilities())
+	assert.Contains(t, desc.String(), "Light Control Set 0, Power: on\n")
+}
+
+func TestDecodeNotification(t *testing.T) {
+	data := `{"9002":1545046543,"9003":7,"9014":0,"9015":1002,"9017":["deviceId=65550"]}`
+	var n Notification
+	require.NoError(t, json.Unmarshal([]byte(data), &n))
+	assert.Equal(t, NotificationNewDevice, n.Event)
+	assert.Equal(t, "New device", n.Event.String())
+	assert.Equal(t, "65550", n.Detail("deviceId"))
+	assert.Equal(t, "Unknown (99)", NotificationEvent(99).String())
+}
+
+func TestWeekdays(t *testing.T) {
+	w, err := ParseWeekdays("mon,Wed, fri")
+	require.NoError(t, err)
+	assert.Equal(t, Monday|Wednesday|Friday, w)
+	assert.Equal(t, "mon,wed,fri", w.String())
+
+	w, err = ParseWeekdays("weekdays")
+	require.NoError(t, err)
+	assert.Equal(t, WorkingDays, w)
+	assert.Equal(t, "daily", (WorkingDays | Weekend).String())
+	assert.Equal(t, "weekend", (Saturday | Sunday).String())
+
+	_, err = ParseWeekdays("mon,funday")
+	assert.Error(t, err)
+}
+
+func TestDaylightSavingFor(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	dst := DaylightSavingFor(london, 2021)
+	assert.Equal(t, DaylightSaving{
+		StartMonth: 3, StartDay: 28, StartHour: 1,
+		EndMonth: 10, EndDay: 31, EndHour: 1,
+		Offset: 60,
+	}, dst)
+	assert.Equal(t, "+60min from 03-28 01:00 to 10-31 01:00 UTC", dst.String())
+
+	// southern hemisphere daylight saving ends before it starts
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	dst = DaylightSavingFor(sydney, 2021)
+	assert.Equal(t, 10, dst.StartMonth)
+	assert.Equal(t, 4, dst.EndMonth)
+
+	// changes on the half hour
+	stJohns, err := time.LoadLocation("America/St_Johns")
+	require.NoError(t, err)
+	assert.Equal(t, "+60min from 03-14 05:30 to 11-07 04:30 UTC", DaylightSavingFor(stJohns, 2021).String())
+	lordHowe, err := time.LoadLocation("Australia/Lord_Howe")
+	require.NoError(t, err)
+	assert.Equal(t, "+30min from 10-02 15:30 to 04-03 15:00 UTC", DaylightSavingFor(lordHowe, 2021).String())
+
+	assert.Equal(t, DaylightSaving{}, DaylightSavingFor(time.UTC, 2021))
+}
+
+func TestStandardOffset(t *testing.T) {
+	for name, offset := range map[string]int{
+		"Europe/Berlin":    60,
+		"Australia/Sydney": 600,
+		"America/St_Johns": -210,
+		"UTC":              0,
+	} {
+		loc, err := time.LoadLocation(name)
+		require.NoError(t, err)
+		assert.Equal(t, offset, standardOffset(loc, 2021), name)
+	}
+}